@@ -46,6 +46,9 @@ type App struct {
 	watch bool
 
 	contextExtractors map[string]func(context.Context) string
+
+	// +optional
+	migrator MigratorFunc
 }
 
 // RunFunc defines the application's startup callback function.
@@ -233,6 +236,10 @@ func (app *App) buildCommand() {
 		AddConfigFlag(fs, app.name, app.watch)
 	}
 
+	if app.migrator != nil {
+		cmd.AddCommand(app.newMigrateCommand(cmd))
+	}
+
 	app.cmd = cmd
 }
 
@@ -242,6 +249,33 @@ func (app *App) Run() {
 }
 
 func (app *App) runCommand(cmd *cobra.Command, args []string) error {
+	if err := app.loadOptions(cmd); err != nil {
+		return err
+	}
+
+	if !app.silence {
+		log.Infow("Starting application", "name", app.name, "version", version.Get().ToJSON())
+		log.Infow("Golang settings", "GOGC", os.Getenv("GOGC"), "GOMAXPROCS", os.Getenv("GOMAXPROCS"), "GOTRACEBACK", os.Getenv("GOTRACEBACK"))
+		if !app.noConfig {
+			PrintConfig()
+		} else if app.options != nil {
+			cliflag.PrintFlags(cmd.Flags())
+		}
+	}
+
+	if app.healthCheckFunc != nil {
+		if err := app.healthCheckFunc(); err != nil {
+			return err
+		}
+	}
+
+	// run application
+	return app.run()
+}
+
+// loadOptions reads the flags and configuration of cmd into the application options,
+// completes and validates them, and initializes the logger.
+func (app *App) loadOptions(cmd *cobra.Command) error {
 	// display application version information
 	version.PrintAndExitIfRequested()
 
@@ -269,24 +303,7 @@ func (app *App) runCommand(cmd *cobra.Command, args []string) error {
 
 	app.initializeLogger()
 
-	if !app.silence {
-		log.Infow("Starting application", "name", app.name, "version", version.Get().ToJSON())
-		log.Infow("Golang settings", "GOGC", os.Getenv("GOGC"), "GOMAXPROCS", os.Getenv("GOMAXPROCS"), "GOTRACEBACK", os.Getenv("GOTRACEBACK"))
-		if !app.noConfig {
-			PrintConfig()
-		} else if app.options != nil {
-			cliflag.PrintFlags(cmd.Flags())
-		}
-	}
-
-	if app.healthCheckFunc != nil {
-		if err := app.healthCheckFunc(); err != nil {
-			return err
-		}
-	}
-
-	// run application
-	return app.run()
+	return nil
 }
 
 // Command returns cobra command instance inside the application.
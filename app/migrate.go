@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/moweilong/mo/store/migrate"
+)
+
+// MigratorFunc creates the schema migrator of the application. It is called once the
+// options of the application are loaded, so it can connect using them.
+type MigratorFunc func() (*migrate.Migrator, error)
+
+// WithMigrator adds the `migrate up|down|status` subcommand, which runs the migrations of
+// the migrator created by fn. The subcommand accepts the same flags and configuration as
+// the application.
+func WithMigrator(fn MigratorFunc) Option {
+	return func(app *App) {
+		app.migrator = fn
+	}
+}
+
+// newMigrateCommand builds the migrate subcommand of root.
+func (app *App) newMigrateCommand(root *cobra.Command) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema migrations",
+		Args:  cobra.NoArgs,
+	}
+	// Flags registered on the root command only are made available to the subcommands.
+	cmd.PersistentFlags().AddFlagSet(root.LocalNonPersistentFlags())
+
+	var upTo int64
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: app.migrateRunE(func(cmd *cobra.Command, m *migrate.Migrator) error {
+			if cmd.Flags().Changed("to") {
+				return m.UpTo(cmd.Context(), upTo)
+			}
+			return m.Up(cmd.Context())
+		}),
+	}
+	up.Flags().Int64Var(&upTo, "to", 0, "Only apply the pending migrations up to this version, never rolling back.")
+
+	var downTo int64
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Roll back the latest migration",
+		Args:  cobra.NoArgs,
+		RunE: app.migrateRunE(func(cmd *cobra.Command, m *migrate.Migrator) error {
+			if cmd.Flags().Changed("to") {
+				return m.To(cmd.Context(), downTo)
+			}
+			return m.Down(cmd.Context())
+		}),
+	}
+	down.Flags().Int64Var(&downTo, "to", 0, "Roll back every migration above this version instead, 0 rolling back all.")
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the migrations",
+		Args:  cobra.NoArgs,
+		RunE: app.migrateRunE(func(cmd *cobra.Command, m *migrate.Migrator) error {
+			statuses, err := m.Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			table := uitable.New()
+			table.MaxColWidth = 80
+			table.AddRow("VERSION", "NAME", "STATE", "APPLIED AT")
+			for _, s := range statuses {
+				appliedAt := "-"
+				if s.AppliedAt != nil {
+					appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+				}
+				table.AddRow(s.Version, s.Name, s.State, appliedAt)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), table)
+			return err
+		}),
+	}
+
+	cmd.AddCommand(up, down, status)
+	return cmd
+}
+
+// migrateRunE returns a cobra run function loading the options and running fn with the
+// migrator of the application.
+func (app *App) migrateRunE(fn func(cmd *cobra.Command, m *migrate.Migrator) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := app.loadOptions(cmd); err != nil {
+			return err
+		}
+
+		m, err := app.migrator()
+		if err != nil {
+			return err
+		}
+		return fn(cmd, m)
+	}
+}
@@ -0,0 +1,20 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Cache.Get when the key is not cached.
+var ErrMiss = errors.New("cache: key not found")
+
+// Cache is a byte-oriented key/value cache.
+type Cache interface {
+	// Get returns the value cached under key, or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set caches value under key for ttl; a zero ttl keeps it until evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Incr atomically increments the integer stored under key, starting from zero, and returns it.
+	Incr(ctx context.Context, key string) (int64, error)
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("connection refused")
+
+// flakyCache is a Cache failing every operation while down.
+type flakyCache struct {
+	Cache
+	down bool
+}
+
+func (c *flakyCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if c.down {
+		return nil, errDown
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *flakyCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.down {
+		return errDown
+	}
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+func (c *flakyCache) Incr(ctx context.Context, key string) (int64, error) {
+	if c.down {
+		return 0, errDown
+	}
+	return c.Cache.Incr(ctx, key)
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("eviction", func(t *testing.T) {
+		c := NewLRU(2)
+		require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, c.Set(ctx, "b", []byte("2"), 0))
+		// Reading a makes b the least recently used key.
+		_, err := c.Get(ctx, "a")
+		require.NoError(t, err)
+		require.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+
+		_, err = c.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrMiss)
+		value, err := c.Get(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("1"), value)
+		value, err = c.Get(ctx, "c")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("3"), value)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c := NewLRU(10)
+		require.NoError(t, c.Set(ctx, "short", []byte("1"), 10*time.Millisecond))
+		require.NoError(t, c.Set(ctx, "forever", []byte("2"), 0))
+		_, err := c.Get(ctx, "short")
+		require.NoError(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+		_, err = c.Get(ctx, "short")
+		assert.ErrorIs(t, err, ErrMiss)
+		_, err = c.Get(ctx, "forever")
+		assert.NoError(t, err)
+	})
+
+	t.Run("incr", func(t *testing.T) {
+		c := NewLRU(10)
+		n, err := c.Incr(ctx, "gen")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		n, err = c.Incr(ctx, "gen")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := &flakyCache{Cache: NewLRU(10)}, NewLRU(10)
+	c := NewFallback(primary, secondary)
+
+	require.NoError(t, c.Set(ctx, "k", []byte("primary"), 0))
+	_, err := secondary.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrMiss, "writes go to the primary while it is up")
+
+	// While the primary is down, the secondary takes over.
+	primary.down = true
+	_, err = c.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrMiss)
+	require.NoError(t, c.Set(ctx, "k", []byte("secondary"), 0))
+	value, err := c.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secondary"), value)
+
+	// Once it recovers, the primary is used again.
+	primary.down = false
+	value, err = c.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("primary"), value)
+
+	// Counters are incremented on both, so that the secondary is invalidated too.
+	n, err := c.Incr(ctx, "gen")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = secondary.Incr(ctx, "gen")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	primary.down = true
+	n, err = c.Incr(ctx, "gen")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var _ Cache = (*Fallback)(nil)
+
+// Fallback is a Cache that uses a primary cache, typically Redis, and switches to a
+// secondary one, typically an LRU, for every operation the primary fails.
+type Fallback struct {
+	primary   Cache
+	secondary Cache
+}
+
+// NewFallback creates a Cache using primary, and secondary while primary is unavailable.
+// Invalidations made on secondary during an outage are not seen by primary once it
+// recovers, so cached values should have a bounded ttl.
+func NewFallback(primary, secondary Cache) *Fallback {
+	return &Fallback{primary: primary, secondary: secondary}
+}
+
+// Get implements Cache.
+func (c *Fallback) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.primary.Get(ctx, key)
+	if err == nil || errors.Is(err, ErrMiss) {
+		return value, err
+	}
+	return c.secondary.Get(ctx, key)
+}
+
+// Set implements Cache.
+func (c *Fallback) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.primary.Set(ctx, key, value, ttl); err != nil {
+		return c.secondary.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+// Incr implements Cache. The secondary counter is always incremented, so that values it
+// cached before an outage of primary are invalidated as well.
+func (c *Fallback) Incr(ctx context.Context, key string) (int64, error) {
+	fallback, err := c.secondary.Incr(ctx, key)
+	if n, perr := c.primary.Incr(ctx, key); perr == nil {
+		return n, nil
+	}
+	return fallback, err
+}
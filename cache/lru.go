@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ Cache = (*LRU)(nil)
+
+// LRU is an in-process Cache holding at most a fixed number of keys, evicting the least
+// recently used ones first.
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is a cached value of an LRU.
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRU creates an LRU holding at most size keys.
+func NewLRU(size int) *LRU {
+	if size <= 0 {
+		size = 1024
+	}
+	return &LRU{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Get implements Cache.
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return nil, ErrMiss
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// Set implements Cache.
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, ttl)
+	return nil
+}
+
+// Incr implements Cache.
+func (c *LRU) Incr(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int64
+	if elem, ok := c.entries[key]; ok {
+		n, _ = strconv.ParseInt(string(elem.Value.(*lruEntry).value), 10, 64)
+	}
+	n++
+	c.set(key, []byte(strconv.FormatInt(n, 10)), 0)
+	return n, nil
+}
+
+// set stores value under key. The caller must hold c.mu.
+func (c *LRU) set(key string, value []byte, ttl time.Duration) {
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove evicts elem. The caller must hold c.mu.
+func (c *LRU) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/moweilong/mo/health"
 )
 
 // RedisOptions defines options for redis database.
@@ -20,9 +22,13 @@ type RedisOptions struct {
 	WriteTimeout time.Duration
 	PoolTimeout  time.Duration
 	PoolSize     int
+	// Connect configures how connecting is retried while Redis is not up yet.
+	// +optional
+	Connect health.Backoff
 }
 
-// NewRedis create a new redis db instance with the given options.
+// NewRedis create a new redis db instance with the given options. While Redis is not up
+// yet, connecting is retried as configured by opts.Connect.
 func NewRedis(opts *RedisOptions) (*redis.Client, error) {
 	options := &redis.Options{
 		Addr:         opts.Addr,
@@ -41,9 +47,24 @@ func NewRedis(opts *RedisOptions) (*redis.Client, error) {
 	rdb := redis.NewClient(options)
 
 	// check redis if is ok
-	if _, err := rdb.Ping(context.Background()).Result(); err != nil {
+	if err := opts.Connect.Retry(context.Background(), "redis", Ping(rdb)); err != nil {
+		_ = rdb.Close()
 		return nil, err
 	}
 
 	return rdb, nil
 }
+
+// Ping returns a health.PingFunc pinging client.
+func Ping(client redis.UniversalClient) health.PingFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// NewProbe creates a health.Probe reporting the readiness of client under name, pinging
+// it every interval. The pings replace the broken connections of the pool, so that Redis
+// is reconnected as soon as it is back.
+func NewProbe(name string, client redis.UniversalClient, interval, timeout time.Duration) *health.Probe {
+	return health.NewProbe(name, Ping(client), interval, timeout)
+}
@@ -0,0 +1,160 @@
+// Package datascope restricts ent queries and mutations to the rows within the data scope of
+// the caller, by owner or department. It mirrors the data scopes of store.WithDataScope.
+package datascope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/mixin"
+)
+
+// ErrMissingDataScope is returned when the context carries no data scope.
+var ErrMissingDataScope = errors.New("missing data scope in context")
+
+// Level is the extent of the rows a caller may access.
+type Level string
+
+const (
+	// LevelAll grants access to every row.
+	LevelAll Level = "all"
+	// LevelDeptAndChildren grants access to the rows of the caller's department and of its descendants.
+	LevelDeptAndChildren Level = "dept_and_children"
+	// LevelDept grants access to the rows of the caller's department.
+	LevelDept Level = "dept"
+	// LevelSelf grants access to the rows owned by the caller.
+	LevelSelf Level = "self"
+)
+
+// Scope is the row-level data permission of a caller.
+type Scope struct {
+	Level Level
+	// UserID is the ID of the caller, matched against the owner field for LevelSelf.
+	UserID any
+	// DeptID is the ID of the caller's department, matched against the department field.
+	DeptID any
+	// ChildDeptIDs are the IDs of the descendants of the caller's department, also accessible
+	// with LevelDeptAndChildren. They are resolved by the caller, e.g. when authenticating.
+	ChildDeptIDs []any
+}
+
+// scopeKey is the context key of the data scope.
+type scopeKey struct{}
+
+// WithScope returns a copy of ctx carrying the data scope of the caller.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// FromContext returns the data scope carried by ctx, if any.
+func FromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// Columns names the fields of a schema that data scopes restrict rows by. A field left
+// empty denies access to callers whose scope level relies on it.
+type Columns struct {
+	// Owner is the field holding the ID of the user owning a row, e.g. created_by.
+	Owner string
+	// Dept is the field holding the ID of the department a row belongs to, e.g. dept_id.
+	Dept string
+}
+
+// Predicate returns the predicate restricting rows to the data scope carried by ctx, e.g. to
+// append to the where selectors of query.BuildQuerySelector. It returns nil when the scope
+// grants access to every row, and ErrMissingDataScope when ctx carries no scope.
+func Predicate(ctx context.Context, columns Columns) (func(*sql.Selector), error) {
+	scope, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrMissingDataScope
+	}
+
+	switch scope.Level {
+	case LevelAll:
+		return nil, nil
+	case LevelSelf:
+		return match(scope.Level, columns.Owner, "user", scope.UserID)
+	case LevelDept:
+		return match(scope.Level, columns.Dept, "department", scope.DeptID)
+	case LevelDeptAndChildren:
+		return match(scope.Level, columns.Dept, "department", scope.DeptID, scope.ChildDeptIDs...)
+	default:
+		return nil, fmt.Errorf("unknown data scope level %q", scope.Level)
+	}
+}
+
+// match builds the predicate matching field against the ID of the caller's user or
+// department and, for departments, the IDs of its descendants.
+func match(level Level, field, kind string, id any, more ...any) (func(*sql.Selector), error) {
+	if field == "" {
+		return nil, fmt.Errorf("data scope %q requires a %s field on the schema", level, kind)
+	}
+	if id == nil {
+		return nil, fmt.Errorf("%w: %s ID", ErrMissingDataScope, kind)
+	}
+
+	if len(more) == 0 {
+		return sql.FieldEQ(field, id), nil
+	}
+	return sql.FieldIn(field, append([]any{id}, more...)...), nil
+}
+
+var _ ent.Mixin = (*Mixin)(nil)
+
+// Mixin restricts the queries, updates and deletes of a schema to the rows within the data
+// scope carried by the context. Creates are not restricted.
+type Mixin struct {
+	mixin.Schema
+
+	Columns Columns
+}
+
+// Interceptors of the data scope mixin.
+func (d Mixin) Interceptors() []ent.Interceptor {
+	return []ent.Interceptor{
+		ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+			w, ok := q.(interface{ WhereP(...func(*sql.Selector)) })
+			if !ok {
+				return fmt.Errorf("datascope: unexpected query type %T", q)
+			}
+			return restrict(ctx, d.Columns, w)
+		}),
+	}
+}
+
+// Hooks of the data scope mixin.
+func (d Mixin) Hooks() []ent.Hook {
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+				if !m.Op().Is(ent.OpUpdate | ent.OpUpdateOne | ent.OpDelete | ent.OpDeleteOne) {
+					return next.Mutate(ctx, m)
+				}
+				w, ok := m.(interface{ WhereP(...func(*sql.Selector)) })
+				if !ok {
+					return nil, fmt.Errorf("datascope: unexpected mutation type %T", m)
+				}
+				if err := restrict(ctx, d.Columns, w); err != nil {
+					return nil, err
+				}
+				return next.Mutate(ctx, m)
+			})
+		},
+	}
+}
+
+// restrict adds the data scope predicate to w.
+func restrict(ctx context.Context, columns Columns, w interface{ WhereP(...func(*sql.Selector)) }) error {
+	p, err := Predicate(ctx, columns)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		w.WhereP(p)
+	}
+	return nil
+}
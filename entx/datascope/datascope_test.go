@@ -0,0 +1,74 @@
+package datascope
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuery struct {
+	predicates []func(*sql.Selector)
+}
+
+func (q *fakeQuery) WhereP(ps ...func(*sql.Selector)) {
+	q.predicates = append(q.predicates, ps...)
+}
+
+var columns = Columns{Owner: "created_by", Dept: "dept_id"}
+
+func TestPredicate(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope Scope
+		query string
+		args  []any
+	}{
+		{"all", Scope{Level: LevelAll}, "SELECT * FROM `tickets`", nil},
+		{"self", Scope{Level: LevelSelf, UserID: 7, DeptID: 1}, "SELECT * FROM `tickets` WHERE `tickets`.`created_by` = ?", []any{7}},
+		{"dept", Scope{Level: LevelDept, UserID: 7, DeptID: 1}, "SELECT * FROM `tickets` WHERE `tickets`.`dept_id` = ?", []any{1}},
+		{"dept and children", Scope{Level: LevelDeptAndChildren, DeptID: 1, ChildDeptIDs: []any{2, 3}}, "SELECT * FROM `tickets` WHERE `tickets`.`dept_id` IN (?, ?, ?)", []any{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Predicate(WithScope(context.Background(), tt.scope), columns)
+			require.NoError(t, err)
+
+			s := sql.Dialect("mysql").Select("*").From(sql.Table("tickets"))
+			if p != nil {
+				p(s)
+			}
+			query, args := s.Query()
+			assert.Equal(t, tt.query, query)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+
+	_, err := Predicate(context.Background(), columns)
+	assert.ErrorIs(t, err, ErrMissingDataScope)
+	_, err = Predicate(WithScope(context.Background(), Scope{Level: LevelSelf}), columns)
+	assert.ErrorIs(t, err, ErrMissingDataScope)
+	_, err = Predicate(WithScope(context.Background(), Scope{Level: LevelDept, DeptID: 1}), Columns{Owner: "created_by"})
+	assert.Error(t, err)
+}
+
+func TestInterceptor(t *testing.T) {
+	interceptors := Mixin{Columns: columns}.Interceptors()
+	require.Len(t, interceptors, 1)
+	traverser, ok := interceptors[0].(ent.Traverser)
+	require.True(t, ok)
+
+	q := &fakeQuery{}
+	require.NoError(t, traverser.Traverse(WithScope(context.Background(), Scope{Level: LevelSelf, UserID: 7}), q))
+	assert.Len(t, q.predicates, 1)
+
+	q = &fakeQuery{}
+	require.NoError(t, traverser.Traverse(WithScope(context.Background(), Scope{Level: LevelAll}), q))
+	assert.Empty(t, q.predicates)
+
+	assert.ErrorIs(t, traverser.Traverse(context.Background(), &fakeQuery{}), ErrMissingDataScope)
+	assert.Error(t, traverser.Traverse(WithScope(context.Background(), Scope{Level: LevelAll}), struct{}{}))
+}
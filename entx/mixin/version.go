@@ -0,0 +1,88 @@
+package mixin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/mixin"
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+)
+
+// FieldVersion is the field holding the optimistic locking version.
+const FieldVersion = "version"
+
+// ErrVersionConflict is returned when an entity was modified concurrently.
+// It carries HTTP status 409 and the same reason as errorsx.ErrVersionConflict.
+var ErrVersionConflict = kerrors.Conflict("VersionConflict", "The resource has been modified by another request. Please reload and try again.")
+
+var _ ent.Mixin = (*Version)(nil)
+
+// Version adds an optimistic locking version field. Setting the version on an UpdateOne
+// mutation to the value the entity was read with makes the update conditional on it and
+// increments it; the update fails with ErrVersionConflict if the entity was modified since.
+// Other updates increment the version unconditionally.
+type Version struct{ mixin.Schema }
+
+func (Version) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int64(FieldVersion).
+			Comment("版本号").
+			Default(1),
+	}
+}
+
+func (Version) Hooks() []ent.Hook {
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+				if !m.Op().Is(ent.OpUpdate | ent.OpUpdateOne) {
+					return next.Mutate(ctx, m)
+				}
+
+				value, ok := m.Field(FieldVersion)
+				expected, isInt := value.(int64)
+				if !ok || !isInt || !m.Op().Is(ent.OpUpdateOne) {
+					m.ResetField(FieldVersion)
+					if err := m.AddField(FieldVersion, int64(1)); err != nil {
+						return nil, err
+					}
+					return next.Mutate(ctx, m)
+				}
+
+				mx, ok := m.(interface{ WhereP(...func(*sql.Selector)) })
+				if !ok {
+					return next.Mutate(ctx, m)
+				}
+				mx.WhereP(sql.FieldEQ(FieldVersion, expected))
+				if err := m.SetField(FieldVersion, expected+1); err != nil {
+					return nil, err
+				}
+
+				v, err := next.Mutate(ctx, m)
+				if isNotFound(err) {
+					return nil, ErrVersionConflict
+				}
+				return v, err
+			})
+		},
+	}
+}
+
+// isNotFound reports whether err is the NotFoundError of the generated ent package,
+// which UpdateOne returns when its predicates match no row.
+func isNotFound(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		t := reflect.TypeOf(err)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Name() == "NotFoundError" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package mixin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type NotFoundError struct{ label string }
+
+func (e *NotFoundError) Error() string { return "ent: " + e.label + " not found" }
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(&NotFoundError{label: "user"}))
+	assert.True(t, isNotFound(fmt.Errorf("wrapped: %w", &NotFoundError{label: "user"})))
+	assert.False(t, isNotFound(errors.New("user not found")))
+	assert.False(t, isNotFound(nil))
+}
+
+func TestErrVersionConflict(t *testing.T) {
+	assert.Equal(t, 409, kerrors.Code(ErrVersionConflict))
+	assert.Equal(t, "VersionConflict", kerrors.Reason(ErrVersionConflict))
+}
@@ -0,0 +1,122 @@
+package softdelete
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/mixin"
+
+	entxmixin "github.com/moweilong/mo/entx/mixin"
+)
+
+const (
+	// FieldDeletedAt is the field set when a row is soft deleted.
+	FieldDeletedAt = "deleted_at"
+	// FieldDeletedBy is the field recording who soft deleted a row.
+	FieldDeletedBy = "deleted_by"
+)
+
+var _ ent.Mixin = (*Mixin)(nil)
+
+// skipKey is the context key that disables soft delete.
+type skipKey struct{}
+
+// SkipSoftDelete returns a copy of ctx for which soft delete is disabled:
+// queries also return soft-deleted rows and deletes remove rows permanently.
+func SkipSoftDelete(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// Skipped reports whether soft delete is disabled for ctx.
+func Skipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(skipKey{}).(bool)
+	return skipped
+}
+
+// Mixin adds the deleted_at field to a schema, hides soft-deleted rows from its queries
+// and turns its deletes into updates of deleted_at.
+type Mixin struct {
+	mixin.Schema
+
+	// DeletedByFunc returns the ID of the operator deleting rows. When set, the schema must
+	// also define the deleted_by field, e.g. with the entx mixin.DeletedBy mixin.
+	DeletedByFunc func(ctx context.Context) (uint32, bool)
+}
+
+// Fields of the soft delete mixin.
+func (Mixin) Fields() []ent.Field {
+	return entxmixin.DeletedAt{}.Fields()
+}
+
+// Interceptors of the soft delete mixin.
+func (Mixin) Interceptors() []ent.Interceptor {
+	return []ent.Interceptor{
+		ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+			if Skipped(ctx) {
+				return nil
+			}
+			w, ok := q.(interface{ WhereP(...func(*sql.Selector)) })
+			if !ok {
+				return fmt.Errorf("softdelete: unexpected query type %T", q)
+			}
+			w.WhereP(sql.FieldIsNull(FieldDeletedAt))
+			return nil
+		}),
+	}
+}
+
+// Hooks of the soft delete mixin.
+func (d Mixin) Hooks() []ent.Hook {
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+				if !m.Op().Is(ent.OpDelete|ent.OpDeleteOne) || Skipped(ctx) {
+					return next.Mutate(ctx, m)
+				}
+
+				mx, ok := m.(interface {
+					SetOp(ent.Op)
+					WhereP(...func(*sql.Selector))
+				})
+				if !ok {
+					return nil, fmt.Errorf("softdelete: unexpected mutation type %T", m)
+				}
+
+				mx.WhereP(sql.FieldIsNull(FieldDeletedAt))
+				mx.SetOp(ent.OpUpdate)
+				if err := m.SetField(FieldDeletedAt, time.Now()); err != nil {
+					return nil, err
+				}
+				if d.DeletedByFunc != nil {
+					if id, ok := d.DeletedByFunc(ctx); ok {
+						if err := m.SetField(FieldDeletedBy, id); err != nil {
+							return nil, err
+						}
+					}
+				}
+				return mutate(ctx, m)
+			})
+		},
+	}
+}
+
+// mutate executes m through the client of the generated mutation, i.e. m.Client().Mutate(ctx, m).
+// The generated client type is not known here, so it is resolved with reflection.
+func mutate(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+	clientMethod := reflect.ValueOf(m).MethodByName("Client")
+	if !clientMethod.IsValid() {
+		return nil, fmt.Errorf("softdelete: mutation %T has no Client method", m)
+	}
+	mutateMethod := clientMethod.Call(nil)[0].MethodByName("Mutate")
+	if !mutateMethod.IsValid() {
+		return nil, fmt.Errorf("softdelete: client of mutation %T has no Mutate method", m)
+	}
+
+	out := mutateMethod.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(m)})
+	err, _ := out[1].Interface().(error)
+	return out[0].Interface(), err
+}
@@ -0,0 +1,41 @@
+package softdelete
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuery struct {
+	predicates []func(*sql.Selector)
+}
+
+func (q *fakeQuery) WhereP(ps ...func(*sql.Selector)) {
+	q.predicates = append(q.predicates, ps...)
+}
+
+func TestInterceptor(t *testing.T) {
+	interceptors := Mixin{}.Interceptors()
+	require.Len(t, interceptors, 1)
+	traverser, ok := interceptors[0].(ent.Traverser)
+	require.True(t, ok)
+
+	q := &fakeQuery{}
+	require.NoError(t, traverser.Traverse(context.Background(), q))
+	require.Len(t, q.predicates, 1)
+
+	s := sql.Dialect("mysql").Select("*").From(sql.Table("users"))
+	q.predicates[0](s)
+	query, _ := s.Query()
+	assert.Equal(t, "SELECT * FROM `users` WHERE `users`.`deleted_at` IS NULL", query)
+
+	q = &fakeQuery{}
+	require.NoError(t, traverser.Traverse(SkipSoftDelete(context.Background()), q))
+	assert.Empty(t, q.predicates)
+
+	assert.Error(t, traverser.Traverse(context.Background(), struct{}{}))
+}
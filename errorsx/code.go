@@ -27,4 +27,7 @@ var (
 
 	// ErrOperationFailed 表示操作失败.
 	ErrOperationFailed = &ErrorX{Code: http.StatusConflict, Reason: "OperationFailed", Message: "The requested operation has failed. Please try again later."}
+
+	// ErrVersionConflict 表示资源已被其他请求修改（乐观锁版本冲突）.
+	ErrVersionConflict = &ErrorX{Code: http.StatusConflict, Reason: "VersionConflict", Message: "The resource has been modified by another request. Please reload and try again."}
 )
@@ -0,0 +1,107 @@
+package gormx
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLOptionsDSN(t *testing.T) {
+	opts := &MySQLOptions{Addr: "db:3306", Username: "app", Password: "secret", Database: "onex"}
+	cfg, err := mysqldriver.ParseDSN(opts.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, "utf8mb4", cfg.Params["charset"])
+	assert.Equal(t, time.Local, cfg.Loc)
+	assert.True(t, cfg.ParseTime)
+	assert.Nil(t, cfg.TLS)
+
+	opts = &MySQLOptions{
+		Addr:             "db:3306",
+		Username:         "app",
+		Password:         "secret",
+		Database:         "onex",
+		Collation:        "utf8mb4_unicode_ci",
+		TimeZone:         "Asia/Shanghai",
+		TLSConfig:        &tls.Config{MinVersion: tls.VersionTLS12},
+		StatementTimeout: 1500 * time.Millisecond,
+		LockTimeout:      1500 * time.Millisecond,
+		ApplicationName:  "apiserver",
+		Params:           map[string]string{"sql_mode": "'STRICT_ALL_TABLES'", "charset": "utf8"},
+	}
+	require.NoError(t, mysqldriver.RegisterTLSConfig(opts.tlsConfigName(), opts.TLSConfig))
+	cfg, err = mysqldriver.ParseDSN(opts.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, "utf8", cfg.Params["charset"])
+	assert.Equal(t, "utf8mb4_unicode_ci", cfg.Collation)
+	assert.Equal(t, "Asia/Shanghai", cfg.Loc.String())
+	require.NotNil(t, cfg.TLS)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.TLS.MinVersion)
+	assert.Equal(t, "db", cfg.TLS.ServerName)
+	assert.Equal(t, "1500", cfg.Params["max_execution_time"])
+	assert.Equal(t, "2", cfg.Params["innodb_lock_wait_timeout"])
+	assert.Equal(t, "program_name:apiserver", cfg.ConnectionAttributes)
+	assert.Equal(t, "'STRICT_ALL_TABLES'", cfg.Params["sql_mode"])
+
+	// Clients of the same server with different TLS configs keep their own.
+	other := *opts
+	other.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS13}
+	assert.NotEqual(t, opts.tlsConfigName(), other.tlsConfigName())
+	require.NoError(t, mysqldriver.RegisterTLSConfig(other.tlsConfigName(), other.TLSConfig))
+	cfg, err = mysqldriver.ParseDSN(opts.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.TLS.MinVersion)
+	cfg, err = mysqldriver.ParseDSN(other.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.TLS.MinVersion)
+
+	// Equal TLS configs, e.g. built anew for every connection pool, share a registration.
+	same := *opts
+	same.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	assert.Equal(t, opts.tlsConfigName(), same.tlsConfigName())
+	verifying := *opts
+	verifying.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, VerifyConnection: func(tls.ConnectionState) error { return nil }}
+	assert.NotEqual(t, opts.tlsConfigName(), verifying.tlsConfigName())
+	assert.Equal(t, verifying.tlsConfigName(), verifying.tlsConfigName())
+}
+
+func TestPostgreSQLOptionsDSN(t *testing.T) {
+	opts := &PostgreSQLOptions{Addr: "db", Username: "app", Password: "it's a secret", Database: "onex"}
+	cfg, err := pgconn.ParseConfig(opts.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, "db", cfg.Host)
+	assert.Equal(t, uint16(5432), cfg.Port)
+	assert.Equal(t, "it's a secret", cfg.Password)
+	assert.Nil(t, cfg.TLSConfig)
+	assert.Equal(t, "Asia/Shanghai", cfg.RuntimeParams["TimeZone"])
+
+	opts = &PostgreSQLOptions{
+		Addr:             "db:6432",
+		Username:         "app",
+		Password:         "secret",
+		Database:         "onex",
+		SSLMode:          "require",
+		TimeZone:         "UTC",
+		SearchPath:       "tenant, public",
+		StatementTimeout: 30 * time.Second,
+		LockTimeout:      time.Second,
+		ApplicationName:  "api server",
+		Params:           map[string]string{"idle_in_transaction_session_timeout": "60000"},
+	}
+	cfg, err = pgconn.ParseConfig(opts.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, uint16(6432), cfg.Port)
+	assert.NotNil(t, cfg.TLSConfig)
+	assert.Equal(t, map[string]string{
+		"TimeZone":                            "UTC",
+		"search_path":                         "tenant, public",
+		"statement_timeout":                   "30000",
+		"lock_timeout":                        "1000",
+		"application_name":                    "api server",
+		"idle_in_transaction_session_timeout": "60000",
+	}, cfg.RuntimeParams)
+}
@@ -0,0 +1,47 @@
+package gormx
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/health"
+)
+
+// openWithRetry calls open until the database is reachable or backoff gives up.
+func openWithRetry(name string, backoff health.Backoff, open func() (*gorm.DB, error)) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := backoff.Retry(context.Background(), name, func(context.Context) error {
+		var err error
+		if db, err = open(); err != nil && db != nil {
+			// gorm.Open returns the instance when the first ping fails, release its connections.
+			if sqlDB, _ := db.DB(); sqlDB != nil {
+				_ = sqlDB.Close()
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Ping returns a health.PingFunc pinging the database of db.
+func Ping(db *gorm.DB) health.PingFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// NewProbe creates a health.Probe reporting the readiness of db under name, pinging it
+// every interval. The pings replace the broken connections of the pool, so that the
+// database is reconnected as soon as it is back.
+func NewProbe(name string, db *gorm.DB, interval, timeout time.Duration) *health.Probe {
+	return health.NewProbe(name, Ping(db), interval, timeout)
+}
@@ -0,0 +1,34 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/health"
+)
+
+func TestOpenWithRetry(t *testing.T) {
+	errDown := errors.New("connection refused")
+	attempts := 0
+	backoff := health.Backoff{MaxWait: time.Second, InitialInterval: time.Millisecond}
+	db, err := openWithRetry("sqlite", backoff, func() (*gorm.DB, error) {
+		if attempts++; attempts < 3 {
+			return nil, errDown
+		}
+		return NewSQLite(&SQLiteOptions{Logger: logger.Discard})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	probe := NewProbe("sqlite", db, 0, time.Second)
+	require.NoError(t, probe.Check(context.Background()))
+	require.NoError(t, MustRawDB(db).Close())
+	assert.Error(t, probe.Check(context.Background()))
+}
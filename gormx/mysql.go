@@ -1,14 +1,25 @@
 package gormx
 
 import (
+	"bytes"
+	"crypto/tls"
 	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"database/sql"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/health"
 )
 
 // MySQLOptions defines options for mysql database.
@@ -20,31 +31,141 @@ type MySQLOptions struct {
 	MaxIdleConnections    int
 	MaxOpenConnections    int
 	MaxConnectionLifeTime time.Duration
+	// Charset is the character set of the connections. Defaults to utf8mb4.
+	Charset string
+	// Collation is the collation of the connections, e.g. utf8mb4_unicode_ci. Defaults to
+	// the default collation of the charset.
+	Collation string
+	// TimeZone is the location of parsed DATETIME and TIMESTAMP values, an IANA time zone
+	// name or Local. Defaults to Local.
+	TimeZone string
+	// TLSConfig enables TLS with the given config, e.g. verifying the server with a CA
+	// and authenticating with a client certificate.
+	// +optional
+	TLSConfig *tls.Config
+	// StatementTimeout aborts SELECT statements running longer, 0 to disable.
+	StatementTimeout time.Duration
+	// LockTimeout is how long statements wait for a row lock, rounded up to seconds. 0 keeps
+	// the server default.
+	LockTimeout time.Duration
+	// ApplicationName identifies the connections as program_name in the connection attributes.
+	ApplicationName string
+	// Params are extra DSN parameters, driver settings or session system variables, taking
+	// precedence over the ones above. String system variables must be quoted, e.g. 'value'.
+	// +optional
+	Params map[string]string
+	// Connect configures how connecting is retried while the database is not up yet.
+	// +optional
+	Connect health.Backoff
 	// +optional
 	Logger logger.Interface
 }
 
-// DSN return DSN from MySQLOptions.
+// DSN return DSN from MySQLOptions. The TLSConfig is referenced by name and must have
+// been registered by NewMySQL.
 func (o *MySQLOptions) DSN() string {
-	return fmt.Sprintf(`%s:%s@tcp(%s)/%s?charset=utf8&parseTime=%t&loc=%s`,
+	charset, timeZone := o.Charset, o.TimeZone
+	if charset == "" {
+		charset = "utf8mb4"
+	}
+	if timeZone == "" {
+		timeZone = "Local"
+	}
+
+	params := url.Values{}
+	params.Set("parseTime", "true")
+	params.Set("charset", charset)
+	params.Set("loc", timeZone)
+	if o.Collation != "" {
+		params.Set("collation", o.Collation)
+	}
+	if o.TLSConfig != nil {
+		params.Set("tls", o.tlsConfigName())
+	}
+	if o.StatementTimeout > 0 {
+		params.Set("max_execution_time", strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10))
+	}
+	if o.LockTimeout > 0 {
+		params.Set("innodb_lock_wait_timeout", strconv.FormatInt(int64(math.Ceil(o.LockTimeout.Seconds())), 10))
+	}
+	if o.ApplicationName != "" {
+		params.Set("connectionAttributes", "program_name:"+o.ApplicationName)
+	}
+	for k, v := range o.Params {
+		params.Set(k, v)
+	}
+
+	return fmt.Sprintf(`%s:%s@tcp(%s)/%s?%s`,
 		o.Username,
 		o.Password,
 		o.Addr,
 		o.Database,
-		true,
-		"Local")
+		params.Encode())
 }
 
-// NewMySQL create a new gorm db instance with the given options.
+var (
+	tlsConfigNamesMu sync.Mutex
+	// tlsConfigNames holds the TLS configs registered with the driver, the name of each being
+	// its index plus one, so that clients of the same server with different TLS configs do
+	// not overwrite each other's while clients with equal configs share a registration.
+	tlsConfigNames []*tls.Config
+)
+
+// tlsConfigName returns the name the TLSConfig is registered under with the driver, shared
+// by the TLS configs equal to it.
+func (o *MySQLOptions) tlsConfigName() string {
+	tlsConfigNamesMu.Lock()
+	defer tlsConfigNamesMu.Unlock()
+
+	i := slices.IndexFunc(tlsConfigNames, func(config *tls.Config) bool {
+		return equalTLSConfigs(config, o.TLSConfig)
+	})
+	if i < 0 {
+		tlsConfigNames = append(tlsConfigNames, o.TLSConfig)
+		i = len(tlsConfigNames) - 1
+	}
+	return "gormx-" + strconv.Itoa(i+1)
+}
+
+// equalTLSConfigs reports whether a and b configure TLS the same way: same root CAs, same
+// client certificates and same other settings. Configs with callbacks are only equal to
+// themselves.
+func equalTLSConfigs(a, b *tls.Config) bool {
+	if a == b {
+		return true
+	}
+	equalChains := func(x, y tls.Certificate) bool {
+		return slices.EqualFunc(x.Certificate, y.Certificate, bytes.Equal)
+	}
+	if !a.RootCAs.Equal(b.RootCAs) || !slices.EqualFunc(a.Certificates, b.Certificates, equalChains) {
+		return false
+	}
+
+	a, b = a.Clone(), b.Clone()
+	a.RootCAs, b.RootCAs = nil, nil
+	a.Certificates, b.Certificates = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// NewMySQL create a new gorm db instance with the given options. While the database is
+// not up yet, connecting is retried as configured by opts.Connect.
 func NewMySQL(opts *MySQLOptions) (*gorm.DB, error) {
 	// Set default values to ensure all fields in opts are available.
 	setMySQLDefaults(opts)
 
-	db, err := gorm.Open(mysql.Open(opts.DSN()), &gorm.Config{
-		// PrepareStmt executes the given query in cached statement.
-		// This can improve performance.
-		PrepareStmt: true,
-		Logger:      opts.Logger,
+	if opts.TLSConfig != nil {
+		if err := mysqldriver.RegisterTLSConfig(opts.tlsConfigName(), opts.TLSConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := openWithRetry("mysql", opts.Connect, func() (*gorm.DB, error) {
+		return gorm.Open(mysql.Open(opts.DSN()), &gorm.Config{
+			// PrepareStmt executes the given query in cached statement.
+			// This can improve performance.
+			PrepareStmt: true,
+			Logger:      opts.Logger,
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -1,8 +1,18 @@
 package gormx
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"k8s.io/klog/v2"
 )
@@ -11,10 +21,31 @@ const (
 	callBackBeforeName = "core:before"
 	callBackAfterName  = "core:after"
 	startTime          = "_start_time"
+	traceSpan          = "_trace_span"
+	traceKind          = "_trace_kind"
+	traceParent        = "_trace_parent"
+
+	// instrumentationName is the name of the tracer and meter of TracePlugin.
+	instrumentationName = "github.com/moweilong/mo/gormx"
 )
 
-// TracePlugin defines gorm plugin used to trace sql.
-type TracePlugin struct{}
+// TracePlugin defines gorm plugin used to trace sql. It creates an OpenTelemetry client span
+// per statement, child of the span carried by the statement context, records the latency and
+// the errors of statements by table and operation, and exposes the connection pool stats.
+// The zero value uses the global OpenTelemetry providers.
+type TracePlugin struct {
+	// TracerProvider creates the tracer of the spans. Defaults to otel.GetTracerProvider().
+	TracerProvider trace.TracerProvider
+	// MeterProvider creates the meter of the metrics. Defaults to otel.GetMeterProvider().
+	MeterProvider metric.MeterProvider
+	// DisablePoolStats disables the connection pool metrics of the underlying sql.DB.
+	DisablePoolStats bool
+
+	tracer   trace.Tracer
+	system   attribute.KeyValue
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
 
 // Name returns the name of trace plugin.
 func (op *TracePlugin) Name() string {
@@ -23,32 +54,83 @@ func (op *TracePlugin) Name() string {
 
 // Initialize initialize the trace plugin.
 func (op *TracePlugin) Initialize(db *gorm.DB) (err error) {
+	tp, mp := op.TracerProvider, op.MeterProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	op.tracer = tp.Tracer(instrumentationName)
+	op.system = dialectorToSemConvKeyValue(db.Dialector.Name())
+
+	meter := mp.Meter(instrumentationName)
+	if op.duration, err = meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of database client operations."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return err
+	}
+	if op.errors, err = meter.Int64Counter("db.client.operation.errors",
+		metric.WithDescription("Number of failed database client operations."),
+		metric.WithUnit("{error}"),
+	); err != nil {
+		return err
+	}
+	if !op.DisablePoolStats {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		if err := otelsql.RegisterDBStatsMetrics(sqlDB, otelsql.WithMeterProvider(mp), otelsql.WithAttributes(op.system)); err != nil {
+			return err
+		}
+	}
+
 	// 开始前
-	_ = db.Callback().Create().Before("gorm:before_create").Register(callBackBeforeName, before)
-	_ = db.Callback().Query().Before("gorm:query").Register(callBackBeforeName, before)
-	_ = db.Callback().Delete().Before("gorm:before_delete").Register(callBackBeforeName, before)
-	_ = db.Callback().Update().Before("gorm:setup_reflect_value").Register(callBackBeforeName, before)
-	_ = db.Callback().Row().Before("gorm:row").Register(callBackBeforeName, before)
-	_ = db.Callback().Raw().Before("gorm:raw").Register(callBackBeforeName, before)
+	_ = db.Callback().Create().Before("gorm:before_create").Register(callBackBeforeName, op.before("create"))
+	_ = db.Callback().Query().Before("gorm:query").Register(callBackBeforeName, op.before("query"))
+	_ = db.Callback().Delete().Before("gorm:before_delete").Register(callBackBeforeName, op.before("delete"))
+	_ = db.Callback().Update().Before("gorm:setup_reflect_value").Register(callBackBeforeName, op.before("update"))
+	_ = db.Callback().Row().Before("gorm:row").Register(callBackBeforeName, op.before("row"))
+	_ = db.Callback().Raw().Before("gorm:raw").Register(callBackBeforeName, op.before("raw"))
 
 	// 结束后
-	_ = db.Callback().Create().After("gorm:after_create").Register(callBackAfterName, after)
-	_ = db.Callback().Query().After("gorm:after_query").Register(callBackAfterName, after)
-	_ = db.Callback().Delete().After("gorm:after_delete").Register(callBackAfterName, after)
-	_ = db.Callback().Update().After("gorm:after_update").Register(callBackAfterName, after)
-	_ = db.Callback().Row().After("gorm:row").Register(callBackAfterName, after)
-	_ = db.Callback().Raw().After("gorm:raw").Register(callBackAfterName, after)
+	_ = db.Callback().Create().After("gorm:after_create").Register(callBackAfterName, op.after)
+	_ = db.Callback().Query().After("gorm:after_query").Register(callBackAfterName, op.after)
+	_ = db.Callback().Delete().After("gorm:after_delete").Register(callBackAfterName, op.after)
+	_ = db.Callback().Update().After("gorm:after_update").Register(callBackAfterName, op.after)
+	_ = db.Callback().Row().After("gorm:row").Register(callBackAfterName, op.after)
+	_ = db.Callback().Raw().After("gorm:raw").Register(callBackAfterName, op.after)
 
 	return
 }
 
 var _ gorm.Plugin = &TracePlugin{}
 
-func before(db *gorm.DB) {
-	db.InstanceSet(startTime, time.Now())
+// before starts the span of a statement of the given kind and makes it the parent of the
+// spans created further down, e.g. by the driver.
+func (op *TracePlugin) before(kind string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, span := op.tracer.Start(parent, "gorm."+kind,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(op.system),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(traceParent, parent)
+		db.InstanceSet(traceSpan, span)
+		db.InstanceSet(traceKind, kind)
+		db.InstanceSet(startTime, time.Now())
+	}
 }
 
-func after(db *gorm.DB) {
+// after ends the span of the statement, records its metrics and restores the context the
+// statement was run with.
+func (op *TracePlugin) after(db *gorm.DB) {
 	_ts, isExist := db.InstanceGet(startTime)
 	if !isExist {
 		return
@@ -58,5 +140,67 @@ func after(db *gorm.DB) {
 	if !ok {
 		return
 	}
-	klog.V(4).Infof("sql cost time: %fs", time.Since(ts).Seconds())
+	elapsed := time.Since(ts)
+	klog.V(4).Infof("sql cost time: %fs", elapsed.Seconds())
+
+	operation := operationName(db)
+	attrs := []attribute.KeyValue{op.system, semconv.DBOperationName(operation)}
+	if db.Statement.Table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(db.Statement.Table))
+	}
+	failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+
+	ctx := db.Statement.Context
+	op.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+	if failed {
+		op.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	if parent, ok := db.InstanceGet(traceParent); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+
+	_span, _ := db.InstanceGet(traceSpan)
+	span, ok := _span.(trace.Span)
+	if !ok {
+		return
+	}
+	// Spans are named "<operation> <table>", e.g. "SELECT users".
+	span.SetName(strings.TrimSpace(operation + " " + db.Statement.Table))
+	span.SetAttributes(attrs...)
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if failed {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+	span.End()
+}
+
+// operationName returns the SQL keyword of the statement, e.g. SELECT, or the kind of the
+// GORM processor when no SQL was built.
+func operationName(db *gorm.DB) string {
+	if sql := strings.TrimSpace(db.Statement.SQL.String()); sql != "" {
+		keyword, _, _ := strings.Cut(sql, " ")
+		return strings.ToUpper(keyword)
+	}
+	kind, _ := db.InstanceGet(traceKind)
+	name, _ := kind.(string)
+	return strings.ToUpper(name)
+}
+
+// dialectorToSemConvKeyValue returns the db.system.name attribute of a GORM dialector.
+func dialectorToSemConvKeyValue(name string) attribute.KeyValue {
+	switch name {
+	case "mysql":
+		return semconv.DBSystemNameMySQL
+	case "postgres":
+		return semconv.DBSystemNamePostgreSQL
+	case "sqlite":
+		return semconv.DBSystemNameSqlite
+	default:
+		return semconv.DBSystemNameKey.String(name)
+	}
 }
@@ -0,0 +1,73 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm/logger"
+)
+
+type tracedUser struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func TestTracePlugin(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	db, err := NewSQLite(&SQLiteOptions{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&tracedUser{}))
+	require.NoError(t, db.Use(&TracePlugin{TracerProvider: tp, MeterProvider: mp}))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "handler")
+	require.NoError(t, db.WithContext(ctx).Create(&tracedUser{Name: "alice"}).Error)
+	var users []tracedUser
+	require.NoError(t, db.WithContext(ctx).Find(&users).Error)
+	assert.Error(t, db.WithContext(ctx).Table("missing").Find(&users).Error)
+	parent.End()
+
+	ended := spans.Ended()
+	require.Len(t, ended, 4)
+	assert.Equal(t, "INSERT traced_users", ended[0].Name())
+	assert.Equal(t, "SELECT traced_users", ended[1].Name())
+	assert.Equal(t, "SELECT missing", ended[2].Name())
+	assert.Equal(t, codes.Error, ended[2].Status().Code)
+	for _, span := range ended[:3] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.String("db.system.name", "sqlite"))
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	duration, ok := metrics["db.client.operation.duration"].(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Len(t, duration.DataPoints, 3)
+
+	errs, ok := metrics["db.client.operation.errors"].(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, errs.DataPoints, 1)
+	assert.Equal(t, int64(1), errs.DataPoints[0].Value)
+	table, _ := errs.DataPoints[0].Attributes.Value("db.collection.name")
+	assert.Equal(t, "missing", table.AsString())
+
+	assert.Contains(t, metrics, "db.sql.connection.open")
+}
@@ -1,13 +1,17 @@
 package gormx
 
 import (
-	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/health"
 )
 
 // PostgreSQLOptions defines options for PostgreSQL database.
@@ -19,6 +23,31 @@ type PostgreSQLOptions struct {
 	MaxIdleConnections    int
 	MaxOpenConnections    int
 	MaxConnectionLifeTime time.Duration
+	// SSLMode is the libpq sslmode: disable, allow, prefer, require, verify-ca or verify-full.
+	// Defaults to disable.
+	SSLMode string
+	// SSLRootCert is the CA certificate file verifying the server with verify-ca and verify-full.
+	SSLRootCert string
+	// SSLCert and SSLKey are the client certificate and key files.
+	SSLCert string
+	SSLKey  string
+	// TimeZone is the time zone of the sessions. Defaults to Asia/Shanghai.
+	TimeZone string
+	// SearchPath is the schema search path of the sessions, e.g. "tenant,public".
+	SearchPath string
+	// StatementTimeout aborts statements running longer, 0 to disable.
+	StatementTimeout time.Duration
+	// LockTimeout aborts statements waiting longer for a lock, 0 to disable.
+	LockTimeout time.Duration
+	// ApplicationName identifies the connections in pg_stat_activity.
+	ApplicationName string
+	// Params are extra DSN parameters, connection settings or run-time parameters, taking
+	// precedence over the ones above.
+	// +optional
+	Params map[string]string
+	// Connect configures how connecting is retried while the database is not up yet.
+	// +optional
+	Connect health.Backoff
 	// +optional
 	Logger logger.Interface
 }
@@ -30,26 +59,72 @@ func (o *PostgreSQLOptions) DSN() string {
 	if len(splited) > 1 {
 		port = splited[1]
 	}
+	sslMode, timeZone := o.SSLMode, o.TimeZone
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	if timeZone == "" {
+		timeZone = "Asia/Shanghai"
+	}
 
-	return fmt.Sprintf(`user=%s password=%s host=%s port=%s dbname=%s sslmode=disable TimeZone=Asia/Shanghai`,
-		o.Username,
-		o.Password,
-		host,
-		port,
-		o.Database,
-	)
+	params := map[string]string{
+		"user":     o.Username,
+		"password": o.Password,
+		"host":     host,
+		"port":     port,
+		"dbname":   o.Database,
+		"sslmode":  sslMode,
+		"TimeZone": timeZone,
+	}
+	optional := map[string]string{
+		"sslrootcert":      o.SSLRootCert,
+		"sslcert":          o.SSLCert,
+		"sslkey":           o.SSLKey,
+		"search_path":      o.SearchPath,
+		"application_name": o.ApplicationName,
+	}
+	if o.StatementTimeout > 0 {
+		optional["statement_timeout"] = strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10)
+	}
+	if o.LockTimeout > 0 {
+		optional["lock_timeout"] = strconv.FormatInt(o.LockTimeout.Milliseconds(), 10)
+	}
+	for k, v := range optional {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	maps.Copy(params, o.Params)
+
+	pairs := make([]string, 0, len(params))
+	for _, k := range slices.Sorted(maps.Keys(params)) {
+		pairs = append(pairs, k+"="+quoteDSNValue(params[k]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// quoteDSNValue quotes a keyword/value DSN value when it is empty or holds spaces,
+// quotes or backslashes.
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
 }
 
-// NewPostgreSQL create a new gorm db instance with the given options.
+// NewPostgreSQL create a new gorm db instance with the given options. While the database
+// is not up yet, connecting is retried as configured by opts.Connect.
 func NewPostgreSQL(opts *PostgreSQLOptions) (*gorm.DB, error) {
 	// Set default values to ensure all fields in opts are available.
 	setPostgreSQLDefaults(opts)
 
-	db, err := gorm.Open(postgres.Open(opts.DSN()), &gorm.Config{
-		// PrepareStmt executes the given query in cached statement.
-		// This can improve performance.
-		PrepareStmt: true,
-		Logger:      opts.Logger,
+	db, err := openWithRetry("postgres", opts.Connect, func() (*gorm.DB, error) {
+		return gorm.Open(postgres.Open(opts.DSN()), &gorm.Config{
+			// PrepareStmt executes the given query in cached statement.
+			// This can improve performance.
+			PrepareStmt: true,
+			Logger:      opts.Logger,
+		})
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,112 @@
+package gormx
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLiteMemory is the SQLiteOptions path of an in-memory database.
+const SQLiteMemory = ":memory:"
+
+// SQLiteOptions defines options for SQLite database.
+type SQLiteOptions struct {
+	// Path is the database file, created if missing, or SQLiteMemory for an in-memory database.
+	Path                  string
+	MaxIdleConnections    int
+	MaxOpenConnections    int
+	MaxConnectionLifeTime time.Duration
+	// BusyTimeout is how long a connection waits for a lock held by another one.
+	BusyTimeout time.Duration
+	// +optional
+	Logger logger.Interface
+}
+
+// DSN return DSN from SQLiteOptions. Foreign keys are enforced, and file databases use
+// write-ahead logging so that readers do not block the writer.
+func (o *SQLiteOptions) DSN() string {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "foreign_keys(1)")
+	pragmas.Add("_pragma", "busy_timeout("+formatMillis(o.BusyTimeout)+")")
+	if !o.isMemory() {
+		pragmas.Add("_pragma", "journal_mode(WAL)")
+	}
+
+	return o.Path + "?" + pragmas.Encode()
+}
+
+// isMemory reports whether the options describe an in-memory database.
+func (o *SQLiteOptions) isMemory() bool {
+	return o.Path == SQLiteMemory || strings.HasPrefix(o.Path, "file::memory:")
+}
+
+// NewSQLite create a new gorm db instance with the given options, using a pure Go driver
+// that needs no cgo. It suits local development and tests.
+//
+// Every connection to an in-memory database would open a distinct database, so in-memory
+// databases use a single connection that is never recycled; they live as long as the
+// returned instance.
+func NewSQLite(opts *SQLiteOptions) (*gorm.DB, error) {
+	// Set default values to ensure all fields in opts are available.
+	setSQLiteDefaults(opts)
+
+	db, err := gorm.Open(sqlite.Open(opts.DSN()), &gorm.Config{
+		// PrepareStmt executes the given query in cached statement.
+		// This can improve performance.
+		PrepareStmt: true,
+		Logger:      opts.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// SetMaxOpenConns sets the maximum number of open connections to the database.
+	sqlDB.SetMaxOpenConns(opts.MaxOpenConnections)
+
+	// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
+	sqlDB.SetConnMaxLifetime(opts.MaxConnectionLifeTime)
+
+	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
+	sqlDB.SetMaxIdleConns(opts.MaxIdleConnections)
+
+	return db, nil
+}
+
+// setSQLiteDefaults set available default values for some fields.
+func setSQLiteDefaults(opts *SQLiteOptions) {
+	if opts.Path == "" {
+		opts.Path = SQLiteMemory
+	}
+	if opts.isMemory() {
+		opts.MaxIdleConnections = 1
+		opts.MaxOpenConnections = 1
+		opts.MaxConnectionLifeTime = 0
+	}
+	if opts.MaxIdleConnections == 0 {
+		opts.MaxIdleConnections = 10
+	}
+	if opts.MaxOpenConnections == 0 {
+		opts.MaxOpenConnections = 10
+	}
+	if opts.BusyTimeout == 0 {
+		opts.BusyTimeout = 5 * time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = logger.Default
+	}
+}
+
+// formatMillis formats d as a number of milliseconds.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
@@ -0,0 +1,81 @@
+// Package health connects to dependencies that may not be up yet and reports whether they
+// are ready, so that services survive rolling deploys and expose their dependency status.
+package health
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/moweilong/mo/log"
+)
+
+const (
+	defaultInitialInterval = 500 * time.Millisecond
+	defaultMaxInterval     = 10 * time.Second
+)
+
+// Backoff configures how a client retries connecting to a dependency. The zero value makes
+// a single attempt.
+type Backoff struct {
+	// MaxWait is how long to keep retrying before giving up, 0 to make a single attempt.
+	MaxWait time.Duration
+	// InitialInterval is the wait after the first failed attempt, doubled after each
+	// failure up to MaxInterval. Defaults to 500ms.
+	InitialInterval time.Duration
+	// MaxInterval caps the wait between attempts. Defaults to 10s.
+	MaxInterval time.Duration
+	// Jitter randomizes each wait by up to this fraction, e.g. 0.2 for ±20%, so that
+	// replicas restarted together do not retry in lockstep. 0 disables it.
+	Jitter float64
+}
+
+// Retry calls connect until it succeeds, MaxWait elapses or ctx is done, waiting with
+// exponential backoff between attempts. It returns the error of the last attempt, wrapped
+// with the name of the dependency when attempts were retried.
+func (b Backoff) Retry(ctx context.Context, name string, connect func(context.Context) error) error {
+	initial, maxInterval := b.InitialInterval, b.MaxInterval
+	if initial <= 0 {
+		initial = defaultInitialInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxInterval
+	}
+
+	deadline := time.Now().Add(b.MaxWait)
+	interval := initial
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("%s is not ready after %d attempts: %w", name, attempt, err)
+		}
+
+		wait := min(b.jitter(interval), remaining)
+		log.Warnw("Dependency is not ready, retrying", "name", name, "attempt", attempt, "retry_in", wait.String(), "err", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s is not ready after %d attempts: %w", name, attempt, err)
+		case <-timer.C:
+		}
+		interval = min(interval*2, maxInterval)
+	}
+}
+
+// jitter randomizes d by up to the Jitter fraction.
+func (b Backoff) jitter(d time.Duration) time.Duration {
+	if b.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + b.Jitter*(2*rand.Float64()-1)))
+}
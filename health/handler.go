@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Status of a dependency or of the whole service in readiness reports.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Report is the readiness of a service and of each of its dependencies.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckReport `json:"checks,omitempty"`
+}
+
+// CheckReport is the readiness of a dependency.
+type CheckReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Check checks the readiness of every checker. The service is ready when all its
+// dependencies are.
+func Check(ctx context.Context, checkers ...Checker) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckReport, len(checkers))}
+	for _, c := range checkers {
+		if err := c.Check(ctx); err != nil {
+			report.Status = StatusUnavailable
+			report.Checks[c.Name()] = CheckReport{Status: StatusUnavailable, Error: err.Error()}
+			continue
+		}
+		report.Checks[c.Name()] = CheckReport{Status: StatusOK}
+	}
+	return report
+}
+
+// Ready returns nil when every checker is ready, or the errors of the unready ones.
+func Ready(ctx context.Context, checkers ...Checker) error {
+	var errs []error
+	for _, c := range checkers {
+		if err := c.Check(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handler returns an http.Handler writing the Report of the checkers as JSON, with status
+// 200 when the service is ready and 503 otherwise.
+func Handler(checkers ...Checker) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		report := Check(r.Context(), checkers...)
+
+		rw.Header().Set("Content-type", "application/json")
+		if report.Status != StatusOK {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			rw.WriteHeader(http.StatusOK)
+		}
+		_ = json.NewEncoder(rw).Encode(report)
+	})
+}
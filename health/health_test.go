@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("connection refused")
+
+// flaky returns a PingFunc failing until it has been called failures times.
+func flaky(failures int32) (PingFunc, *atomic.Int32) {
+	var calls atomic.Int32
+	return func(context.Context) error {
+		if calls.Add(1) <= failures {
+			return errDown
+		}
+		return nil
+	}, &calls
+}
+
+func TestBackoffRetry(t *testing.T) {
+	ctx := context.Background()
+	fast := Backoff{MaxWait: time.Second, InitialInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond, Jitter: 0.5}
+
+	t.Run("single attempt", func(t *testing.T) {
+		ping, calls := flaky(1)
+		assert.Equal(t, errDown, Backoff{}.Retry(ctx, "db", ping))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("until ready", func(t *testing.T) {
+		ping, calls := flaky(3)
+		require.NoError(t, fast.Retry(ctx, "db", ping))
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("max wait", func(t *testing.T) {
+		ping, calls := flaky(1000)
+		b := fast
+		b.MaxWait = 20 * time.Millisecond
+		start := time.Now()
+		err := b.Retry(ctx, "db", ping)
+		assert.ErrorIs(t, err, errDown)
+		assert.ErrorContains(t, err, "db is not ready")
+		assert.Greater(t, calls.Load(), int32(1))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		ping, calls := flaky(1000)
+		assert.ErrorIs(t, fast.Retry(ctx, "db", ping), errDown)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestProbe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("background pings", func(t *testing.T) {
+		var down atomic.Bool
+		down.Store(true)
+		p := NewProbe("db", func(context.Context) error {
+			if down.Load() {
+				return errDown
+			}
+			return nil
+		}, time.Millisecond, time.Second)
+		defer p.Close()
+
+		assert.Eventually(t, func() bool { return errors.Is(p.Check(ctx), errDown) }, time.Second, time.Millisecond)
+		down.Store(false)
+		assert.Eventually(t, func() bool { return p.Check(ctx) == nil }, time.Second, time.Millisecond)
+	})
+
+	t.Run("on demand", func(t *testing.T) {
+		ping, calls := flaky(1)
+		p := NewProbe("db", ping, 0, time.Second)
+		assert.Equal(t, errDown, p.Check(ctx))
+		assert.NoError(t, p.Check(ctx))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		p := NewProbe("db", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, 0, time.Millisecond)
+		assert.ErrorIs(t, p.Check(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	up := NewProbe("redis", func(context.Context) error { return nil }, 0, 0)
+	down := NewProbe("mysql", func(context.Context) error { return errDown }, 0, 0)
+
+	serve := func(checkers ...Checker) (int, Report) {
+		rec := httptest.NewRecorder()
+		Handler(checkers...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return rec.Code, report
+	}
+
+	code, report := serve(up)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Report{Status: StatusOK, Checks: map[string]CheckReport{"redis": {Status: StatusOK}}}, report)
+
+	code, report = serve(up, down)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, CheckReport{Status: StatusUnavailable, Error: errDown.Error()}, report.Checks["mysql"])
+
+	err := Ready(context.Background(), up, down)
+	assert.ErrorIs(t, err, errDown)
+	assert.ErrorContains(t, err, "mysql: ")
+}
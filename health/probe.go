@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moweilong/mo/log"
+)
+
+// ErrNotChecked is reported by a Probe until its first ping completes.
+var ErrNotChecked = errors.New("health: not checked yet")
+
+// Checker reports the readiness of a dependency.
+type Checker interface {
+	// Name returns the name of the dependency, e.g. mysql.
+	Name() string
+	// Check returns nil when the dependency is ready, or why it is not.
+	Check(ctx context.Context) error
+}
+
+// PingFunc checks that a dependency is reachable.
+type PingFunc func(ctx context.Context) error
+
+// Probe is a Checker pinging a dependency in the background. Database and Redis clients
+// replace broken connections when pinged, so the pings also reconnect the client once the
+// dependency is back. Check returns the result of the last ping and never blocks.
+type Probe struct {
+	name     string
+	ping     PingFunc
+	interval time.Duration
+	timeout  time.Duration
+
+	mu  sync.RWMutex
+	err error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ Checker = (*Probe)(nil)
+
+// NewProbe creates a Probe and starts pinging the dependency immediately, then every
+// interval, each ping failing after timeout. A zero interval disables the background
+// pings; Check then pings the dependency itself. Call Close to stop the pings.
+func NewProbe(name string, ping PingFunc, interval, timeout time.Duration) *Probe {
+	p := &Probe{
+		name:     name,
+		ping:     ping,
+		interval: interval,
+		timeout:  timeout,
+		err:      ErrNotChecked,
+		stop:     make(chan struct{}),
+	}
+	if interval > 0 {
+		go p.loop()
+	}
+	return p
+}
+
+// Name returns the name of the dependency.
+func (p *Probe) Name() string {
+	return p.name
+}
+
+// Check returns the result of the last ping, or pings the dependency when the background
+// pings are disabled.
+func (p *Probe) Check(ctx context.Context) error {
+	if p.interval <= 0 {
+		return p.Ping(ctx)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.err
+}
+
+// Ping pings the dependency once and records the result, logging when the readiness of
+// the dependency changes.
+func (p *Probe) Ping(ctx context.Context) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	err := p.ping(ctx)
+
+	p.mu.Lock()
+	prev := p.err
+	p.err = err
+	p.mu.Unlock()
+
+	switch {
+	case err != nil && prev == nil:
+		log.Errorw(err, "Dependency became unavailable", "name", p.name)
+	case err == nil && prev != nil && !errors.Is(prev, ErrNotChecked):
+		log.Infow("Dependency recovered", "name", p.name)
+	}
+	return err
+}
+
+// Close stops the background pings. It does not close the client.
+func (p *Probe) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// loop pings the dependency every interval until Close is called.
+func (p *Probe) loop() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		_ = p.Ping(context.Background())
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
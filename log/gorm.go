@@ -2,105 +2,135 @@ package log
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"path/filepath"
-	"runtime"
-	"strconv"
-	"strings"
 	"time"
 
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
 )
 
-var (
-	infoStr       = "%s[info] "
-	warnStr       = "%s[warn] "
-	errStr        = "%s[error] "
-	traceStr      = "[%s][%.3fms] [rows:%v] %s"
-	traceWarnStr  = "%s %s[%.3fms] [rows:%v] %s"
-	traceErrStr   = "%s %s[%.3fms] [rows:%v] %s"
-	slowThreshold = 200 * time.Millisecond
-)
+// defaultSlowThreshold is the default duration above which statements are logged as slow.
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// gormConfig configures the GORM logging of a zapLogger.
+type gormConfig struct {
+	level                gormlogger.LogLevel
+	slowThreshold        time.Duration
+	ignoreRecordNotFound bool
+	redactParams         bool
+}
 
 var levelM = map[string]gormlogger.LogLevel{
-	"panic": gormlogger.Silent,
-	"error": gormlogger.Error,
-	"warn":  gormlogger.Warn,
+	"debug": gormlogger.Info,
 	"info":  gormlogger.Info,
+	"warn":  gormlogger.Warn,
+	"error": gormlogger.Error,
 }
 
-func (l *zapLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
-	opts := *l.opts
-	switch {
-	case level <= gormlogger.Silent:
-		opts.Level = "panic"
-	case level <= gormlogger.Error:
-		opts.Level = "error"
-	case level <= gormlogger.Warn:
-		opts.Level = "warn"
-	case level <= gormlogger.Info:
-		opts.Level = "info"
-	default:
+// newGormConfig returns the default GORM logging configuration for the given log level.
+func newGormConfig(level string) gormConfig {
+	cfg := gormConfig{level: gormlogger.Silent, slowThreshold: defaultSlowThreshold}
+	if l, ok := levelM[level]; ok {
+		cfg.level = l
 	}
+	return cfg
+}
 
-	return NewLogger(&opts)
+// WithGormSlowThreshold sets the duration above which GORM statements are logged as slow
+// at warn level, 0 to disable slow statement logging. Defaults to 200ms.
+func WithGormSlowThreshold(threshold time.Duration) Option {
+	return func(l *zapLogger) {
+		l.gorm.slowThreshold = threshold
+	}
+}
+
+// WithGormIgnoreRecordNotFound stops logging gorm.ErrRecordNotFound errors of GORM statements.
+func WithGormIgnoreRecordNotFound() Option {
+	return func(l *zapLogger) {
+		l.gorm.ignoreRecordNotFound = true
+	}
+}
+
+// WithGormRedactParams logs GORM statements with placeholders instead of their bound
+// parameters, which may hold personal data or secrets.
+func WithGormRedactParams() Option {
+	return func(l *zapLogger) {
+		l.gorm.redactParams = true
+	}
+}
+
+// LogMode returns a copy of the logger logging GORM statements at the given level. The
+// context extractors and the other GORM settings are kept.
+func (l *zapLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	lc := l.clone()
+	lc.gorm.level = level
+	return lc
+}
+
+// ParamsFilter implements gorm.ParamsFilter, dropping the bound parameters of the
+// logged statements when they are redacted.
+func (l *zapLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.gorm.redactParams {
+		return sql, nil
+	}
+	return sql, params
 }
 
 func (l *zapLogger) Info(ctx context.Context, msg string, keyvals ...any) {
-	l.z.Sugar().Infof(infoStr+msg, append([]any{fileWithLineNum()}, keyvals...)...)
+	if l.gorm.level >= gormlogger.Info {
+		l.gormLogger(ctx).log(zapcore.InfoLevel, fmt.Sprintf(msg, keyvals...), "caller", utils.FileWithLineNum())
+	}
 }
 
 func (l *zapLogger) Warn(ctx context.Context, msg string, keyvals ...any) {
-	l.z.Sugar().Warnf(warnStr+msg, append([]any{fileWithLineNum()}, keyvals...)...)
+	if l.gorm.level >= gormlogger.Warn {
+		l.gormLogger(ctx).log(zapcore.WarnLevel, fmt.Sprintf(msg, keyvals...), "caller", utils.FileWithLineNum())
+	}
 }
 
 func (l *zapLogger) Error(ctx context.Context, msg string, keyvals ...any) {
-	l.z.Sugar().Errorf(errStr+msg, append([]any{fileWithLineNum()}, keyvals...)...)
+	if l.gorm.level >= gormlogger.Error {
+		l.gormLogger(ctx).log(zapcore.ErrorLevel, fmt.Sprintf(msg, keyvals...), "caller", utils.FileWithLineNum())
+	}
 }
 
+// Trace logs a GORM statement with the sql, rows, elapsed_ms and caller fields: at error
+// level when it failed, at warn level when it was slow and at info level otherwise.
 func (l *zapLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
-	if levelM[l.opts.Level] <= gormlogger.Silent {
+	if l.gorm.level <= gormlogger.Silent {
 		return
 	}
 
 	elapsed := time.Since(begin)
-	switch {
-	case err != nil && levelM[l.opts.Level] >= gormlogger.Error:
-		sql, rows := fc()
-		if rows == -1 {
-			l.z.Sugar().Errorf(traceErrStr, fileWithLineNum(), err, float64(elapsed.Nanoseconds())/1e6, "-", sql)
-		} else {
-			l.z.Sugar().Errorf(traceErrStr, fileWithLineNum(), err, float64(elapsed.Nanoseconds())/1e6, rows, sql)
-		}
-	case elapsed > slowThreshold && slowThreshold != 0 && levelM[l.opts.Level] >= gormlogger.Warn:
-		sql, rows := fc()
-		slowLog := fmt.Sprintf("SLOW SQL >= %v", slowThreshold)
-		if rows == -1 {
-			l.z.Sugar().Warnf(traceWarnStr, fileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, "-", sql)
-		} else {
-			l.z.Sugar().Warnf(traceWarnStr, fileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, rows, sql)
-		}
-	case levelM[l.opts.Level] >= gormlogger.Info:
+	fields := func() []any {
 		sql, rows := fc()
-		if rows == -1 {
-			l.z.Sugar().Infof(traceStr, fileWithLineNum(), float64(elapsed.Nanoseconds())/1e6, "-", sql)
-		} else {
-			l.z.Sugar().Infof(traceStr, fileWithLineNum(), float64(elapsed.Nanoseconds())/1e6, rows, sql)
+		return []any{
+			"sql", sql,
+			"rows", rows,
+			"elapsed_ms", float64(elapsed.Nanoseconds()) / 1e6,
+			"caller", utils.FileWithLineNum(),
 		}
 	}
-}
-
-func fileWithLineNum() string {
-	for i := 4; i < 15; i++ {
-		_, file, line, ok := runtime.Caller(i)
 
-		// if ok && (!strings.HasPrefix(file, gormSourceDir) || strings.HasSuffix(file, "_test.go")) {
-		if ok && !strings.HasSuffix(file, "_test.go") {
-			dir, f := filepath.Split(file)
-
-			return filepath.Join(filepath.Base(dir), f) + ":" + strconv.FormatInt(int64(line), 10)
-		}
+	switch {
+	case err != nil && l.gorm.level >= gormlogger.Error &&
+		(!l.gorm.ignoreRecordNotFound || !errors.Is(err, gorm.ErrRecordNotFound)):
+		l.gormLogger(ctx).Errorw(err, "SQL error", fields()...)
+	case l.gorm.slowThreshold != 0 && elapsed > l.gorm.slowThreshold && l.gorm.level >= gormlogger.Warn:
+		l.gormLogger(ctx).Warnw("Slow SQL", append(fields(), "slow_threshold_ms", float64(l.gorm.slowThreshold.Nanoseconds())/1e6)...)
+	case l.gorm.level >= gormlogger.Info:
+		l.gormLogger(ctx).Infow("SQL", fields()...)
 	}
+}
 
-	return ""
+// gormLogger returns the logger of GORM messages for ctx. The caller is reported in the
+// caller field by the GORM methods, since zap would report GORM internals.
+func (l *zapLogger) gormLogger(ctx context.Context) *zapLogger {
+	lc := l.withContext(ctx)
+	lc.z = lc.z.WithOptions(zap.WithCaller(false))
+	return lc
 }
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type requestIDKey struct{}
+
+// newObservedLogger creates a logger recording its entries, with a request_id extractor.
+func newObservedLogger(options ...Option) (*zapLogger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := &zapLogger{
+		z:                 zap.New(core),
+		opts:              NewOptions(),
+		contextExtractors: map[string]func(context.Context) string{},
+		gorm:              newGormConfig("info"),
+	}
+	options = append([]Option{WithContextExtractor(ContextExtractors{
+		"request_id": func(ctx context.Context) string {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return id
+		},
+	})}, options...)
+	for _, opt := range options {
+		opt(l)
+	}
+	return l, logs
+}
+
+func TestGormTrace(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	sql := func() (string, int64) { return "SELECT * FROM users WHERE id = 1", 1 }
+
+	t.Run("structured fields", func(t *testing.T) {
+		l, logs := newObservedLogger()
+		// LogMode keeps the context extractors.
+		gl := l.LogMode(gormlogger.Info)
+		gl.Trace(ctx, time.Now(), sql, nil)
+
+		require.Equal(t, 1, logs.Len())
+		entry := logs.All()[0]
+		assert.Equal(t, zapcore.InfoLevel, entry.Level)
+		fields := entry.ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "SELECT * FROM users WHERE id = 1", fields["sql"])
+		assert.Equal(t, int64(1), fields["rows"])
+		assert.Contains(t, fields, "elapsed_ms")
+		assert.Contains(t, fields, "caller")
+	})
+
+	t.Run("levels", func(t *testing.T) {
+		l, logs := newObservedLogger(WithGormSlowThreshold(time.Millisecond))
+		gl := l.LogMode(gormlogger.Warn)
+		gl.Trace(ctx, time.Now(), sql, nil)
+		gl.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+		gl.Trace(ctx, time.Now(), sql, errors.New("boom"))
+		gl.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+		l.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), sql, errors.New("boom"))
+
+		entries := logs.All()
+		require.Len(t, entries, 3)
+		assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+		assert.Equal(t, 1.0, entries[0].ContextMap()["slow_threshold_ms"])
+		assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+		assert.Equal(t, "boom", entries[1].ContextMap()["err"])
+		assert.Equal(t, zapcore.ErrorLevel, entries[2].Level)
+	})
+
+	t.Run("ignore record not found", func(t *testing.T) {
+		l, logs := newObservedLogger(WithGormIgnoreRecordNotFound())
+		l.LogMode(gormlogger.Warn).Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+		assert.Zero(t, logs.Len())
+	})
+
+	t.Run("redact params", func(t *testing.T) {
+		l, _ := newObservedLogger(WithGormRedactParams())
+		filter, ok := l.LogMode(gormlogger.Info).(gorm.ParamsFilter)
+		require.True(t, ok)
+		query, params := filter.ParamsFilter(ctx, "SELECT * FROM users WHERE phone = ?", "13800000000")
+		assert.Equal(t, "SELECT * FROM users WHERE phone = ?", query)
+		assert.Empty(t, params)
+
+		l, _ = newObservedLogger()
+		_, params = l.ParamsFilter(ctx, "SELECT * FROM users WHERE phone = ?", "13800000000")
+		assert.Equal(t, []any{"13800000000"}, params)
+	})
+}
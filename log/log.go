@@ -43,6 +43,7 @@ type zapLogger struct {
 	z                 *zap.Logger
 	opts              *Options
 	contextExtractors map[string]func(context.Context) string // 定义从 context 中提取字段的映射
+	gorm              gormConfig                              // 作为 GORM 日志器时的配置
 }
 
 // Option 是一个函数类型，用于配置 zapLogger 的选项
@@ -69,7 +70,7 @@ func WithContextExtractor(contextExtractors ContextExtractors) Option {
 func Init(opts *Options, options ...Option) {
 	mu.Lock()
 	defer mu.Unlock()
-	std = NewLogger(opts)
+	std = NewLogger(opts, options...)
 }
 
 // NewLogger 根据传入的 opts 创建 Logger.
@@ -133,7 +134,12 @@ func NewLogger(opts *Options, options ...Option) *zapLogger {
 		panic(err)
 	}
 
-	logger := &zapLogger{z: z, opts: opts, contextExtractors: make(map[string]func(context.Context) string)}
+	logger := &zapLogger{
+		z:                 z,
+		opts:              opts,
+		contextExtractors: make(map[string]func(context.Context) string),
+		gorm:              newGormConfig(opts.Level),
+	}
 	// 应用所有传入的 Option
 	for _, opt := range options {
 		opt(logger)
@@ -205,6 +211,11 @@ func W(ctx context.Context) Logger {
 
 // W 方法，根据 context 提取字段并添加到日志中
 func (l *zapLogger) W(ctx context.Context) Logger {
+	return l.withContext(ctx)
+}
+
+// withContext 返回添加了 context 中提取字段的 zapLogger 副本
+func (l *zapLogger) withContext(ctx context.Context) *zapLogger {
 	lc := l.clone()
 
 	for fieldName, extractor := range l.contextExtractors {
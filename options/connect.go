@@ -0,0 +1,40 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moweilong/mo/health"
+)
+
+// defaultConnectJitter is the default jitter of the waits between connection attempts.
+const defaultConnectJitter = 0.2
+
+// validateConnect verifies the connection retry options.
+func validateConnect(maxWait time.Duration, jitter float64) []error {
+	var errs []error
+	if maxWait < 0 {
+		errs = append(errs, fmt.Errorf("connect max wait %s must not be negative", maxWait))
+	}
+	if jitter < 0 || jitter > 1 {
+		errs = append(errs, fmt.Errorf("connect jitter %g must be between 0 and 1", jitter))
+	}
+	return errs
+}
+
+// validateSessionTimeouts verifies the statement and lock timeouts of database sessions.
+func validateSessionTimeouts(statementTimeout, lockTimeout time.Duration) []error {
+	var errs []error
+	if statementTimeout < 0 {
+		errs = append(errs, fmt.Errorf("statement timeout %s must not be negative", statementTimeout))
+	}
+	if lockTimeout < 0 {
+		errs = append(errs, fmt.Errorf("lock timeout %s must not be negative", lockTimeout))
+	}
+	return errs
+}
+
+// connectBackoff returns the backoff of the connection attempts.
+func connectBackoff(maxWait time.Duration, jitter float64) health.Backoff {
+	return health.Backoff{MaxWait: maxWait, Jitter: jitter}
+}
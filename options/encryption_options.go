@@ -0,0 +1,66 @@
+package options
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/moweilong/mo/store/encrypt"
+)
+
+var _ IOptions = (*EncryptionOptions)(nil)
+
+// EncryptionOptions defines the keyring of the field-level encryption of GORM models.
+type EncryptionOptions struct {
+	// PrimaryKeyID is the ID of the key encrypting new values.
+	PrimaryKeyID string `json:"primary-key-id" mapstructure:"primary-key-id"`
+	// Keys maps key IDs to base64-encoded AES keys of 16, 24 or 32 bytes. Keys retired from
+	// encryption are kept to decrypt existing values.
+	Keys map[string]string `json:"-" mapstructure:"keys"`
+}
+
+// NewEncryptionOptions create a `zero` value instance.
+func NewEncryptionOptions() *EncryptionOptions {
+	return &EncryptionOptions{
+		Keys: map[string]string{},
+	}
+}
+
+// Validate verifies flags passed to EncryptionOptions.
+func (o *EncryptionOptions) Validate() []error {
+	errs := []error{}
+
+	if len(o.Keys) == 0 {
+		return errs
+	}
+
+	if _, err := o.NewKeyring(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// AddFlags adds flags related to field-level encryption to the specified FlagSet.
+func (o *EncryptionOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.StringVar(&o.PrimaryKeyID, join(prefixes...)+"encryption.primary-key-id", o.PrimaryKeyID, ""+
+		"ID of the encryption key used to encrypt new values.")
+	fs.StringToStringVar(&o.Keys, join(prefixes...)+"encryption.keys", o.Keys, ""+
+		"Encryption keys as id=base64 pairs of AES keys of 16, 24 or 32 bytes.")
+}
+
+// NewKeyring creates the keyring of the configured keys, to be registered with
+// encrypt.RegisterKeyring.
+func (o *EncryptionOptions) NewKeyring() (*encrypt.Keyring, error) {
+	keys := make(map[string][]byte, len(o.Keys))
+	for id, encoded := range o.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return encrypt.NewKeyring(o.PrimaryKeyID, keys)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/spf13/pflag"
 
+	"github.com/moweilong/mo/health"
 	"github.com/moweilong/mo/log"
 )
 
@@ -19,6 +20,8 @@ type HealthOptions struct {
 	HTTPProfile        bool   `json:"enable-http-profiler" mapstructure:"enable-http-profiler"`
 	HealthCheckPath    string `json:"check-path" mapstructure:"check-path"`
 	HealthCheckAddress string `json:"check-address" mapstructure:"check-address"`
+	// ReadinessCheckPath reports the readiness of the dependencies passed to ServeHealthCheck.
+	ReadinessCheckPath string `json:"readiness-path" mapstructure:"readiness-path"`
 }
 
 // NewHealthOptions create a `zero` value instance.
@@ -27,6 +30,7 @@ func NewHealthOptions() *HealthOptions {
 		HTTPProfile:        false,
 		HealthCheckPath:    "/healthz",
 		HealthCheckAddress: "0.0.0.0:20250",
+		ReadinessCheckPath: "/readyz",
 	}
 }
 
@@ -42,18 +46,25 @@ func (o *HealthOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
 	fs.BoolVar(&o.HTTPProfile, "health.enable-http-profiler", o.HTTPProfile, "Expose runtime profiling data via HTTP.")
 	fs.StringVar(&o.HealthCheckPath, "health.check-path", o.HealthCheckPath, "Specifies liveness health check request path.")
 	fs.StringVar(&o.HealthCheckAddress, "health.check-address", o.HealthCheckAddress, "Specifies liveness health check bind address.")
+	fs.StringVar(&o.ReadinessCheckPath, "health.readiness-path", o.ReadinessCheckPath, ""+
+		"Specifies readiness check request path, reporting the status of the dependencies.")
 }
 
-func (o *HealthOptions) ServeHealthCheck() {
+// ServeHealthCheck serves the liveness check and, when set, the readiness check reporting
+// the status of the given dependencies: 200 when all of them are ready, 503 otherwise.
+func (o *HealthOptions) ServeHealthCheck(checkers ...health.Checker) {
 	r := mux.NewRouter()
 
 	r.HandleFunc(o.HealthCheckPath, handler).Methods(http.MethodGet)
+	if o.ReadinessCheckPath != "" {
+		r.Handle(o.ReadinessCheckPath, health.Handler(checkers...)).Methods(http.MethodGet)
+	}
 	if o.HTTPProfile {
 		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
 		r.HandleFunc("/debug/pprof/{_:.*}", pprof.Index)
 	}
 
-	log.Infow("Starting health check server", "path", o.HealthCheckPath, "addr", o.HealthCheckAddress)
+	log.Infow("Starting health check server", "path", o.HealthCheckPath, "readiness_path", o.ReadinessCheckPath, "addr", o.HealthCheckAddress)
 	if err := http.ListenAndServe(o.HealthCheckAddress, r); err != nil {
 		log.Fatalf("Error serving health check endpoint: %v", err)
 	}
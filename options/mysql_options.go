@@ -10,6 +10,7 @@ import (
 
 	"github.com/moweilong/mo/gormx"
 	"github.com/moweilong/mo/log"
+	"github.com/moweilong/mo/store"
 )
 
 var _ IOptions = (*MySQLOptions)(nil)
@@ -24,19 +25,51 @@ type MySQLOptions struct {
 	MaxOpenConnections    int           `json:"max-open-connections,omitempty" mapstructure:"max-open-connections"`
 	MaxConnectionLifeTime time.Duration `json:"max-connection-life-time,omitempty" mapstructure:"max-connection-life-time"`
 	LogLevel              int           `json:"log-level" mapstructure:"log-level"`
+	// Replicas are the addresses of read replicas, sharing the credentials and database of the primary.
+	Replicas []string `json:"replicas,omitempty" mapstructure:"replicas"`
+	// ReplicaPolicy is the load-balancing policy across replicas, "round-robin" or "random".
+	ReplicaPolicy string `json:"replica-policy,omitempty" mapstructure:"replica-policy"`
+	// ReplicaHealthCheckInterval is how often replicas are pinged; unreachable ones are ejected until they recover.
+	ReplicaHealthCheckInterval time.Duration `json:"replica-health-check-interval,omitempty" mapstructure:"replica-health-check-interval"`
+	// ConnectMaxWait is how long connecting is retried while the database is not up yet, 0 to fail at once.
+	ConnectMaxWait time.Duration `json:"connect-max-wait,omitempty" mapstructure:"connect-max-wait"`
+	// ConnectJitter randomizes the waits between connection attempts by up to this fraction.
+	ConnectJitter float64 `json:"connect-jitter,omitempty" mapstructure:"connect-jitter"`
+	// Charset is the character set of the connections.
+	Charset string `json:"charset,omitempty" mapstructure:"charset"`
+	// Collation is the collation of the connections, empty for the default collation of the charset.
+	Collation string `json:"collation,omitempty" mapstructure:"collation"`
+	// TimeZone is the location of parsed DATETIME and TIMESTAMP values, an IANA time zone name or Local.
+	TimeZone string `json:"timezone,omitempty" mapstructure:"timezone"`
+	// StatementTimeout aborts SELECT statements running longer, 0 to disable.
+	StatementTimeout time.Duration `json:"statement-timeout,omitempty" mapstructure:"statement-timeout"`
+	// LockTimeout is how long statements wait for a lock, 0 for the server default.
+	LockTimeout time.Duration `json:"lock-timeout,omitempty" mapstructure:"lock-timeout"`
+	// ApplicationName identifies the connections of the application on the server.
+	ApplicationName string `json:"application-name,omitempty" mapstructure:"application-name"`
+	// Params are extra DSN parameters, taking precedence over the other options.
+	Params map[string]string `json:"params,omitempty" mapstructure:"params"`
+	// TLSOptions configures TLS, with a CA certificate verifying the server and a client certificate.
+	TLSOptions *TLSOptions `json:"tls" mapstructure:"tls"`
 }
 
 // NewMySQLOptions create a `zero` value instance.
 func NewMySQLOptions() *MySQLOptions {
 	return &MySQLOptions{
-		Addr:                  "127.0.0.1:3306",
-		Username:              "onex",
-		Password:              "onex(#)666",
-		Database:              "onex",
-		MaxIdleConnections:    100,
-		MaxOpenConnections:    100,
-		MaxConnectionLifeTime: time.Duration(10) * time.Second,
-		LogLevel:              1, // Silent
+		Addr:                       "127.0.0.1:3306",
+		Username:                   "onex",
+		Password:                   "onex(#)666",
+		Database:                   "onex",
+		MaxIdleConnections:         100,
+		MaxOpenConnections:         100,
+		MaxConnectionLifeTime:      time.Duration(10) * time.Second,
+		LogLevel:                   1, // Silent
+		ReplicaPolicy:              ReplicaPolicyRoundRobin,
+		ReplicaHealthCheckInterval: 10 * time.Second,
+		ConnectJitter:              defaultConnectJitter,
+		Charset:                    "utf8mb4",
+		TimeZone:                   "Local",
+		TLSOptions:                 NewTLSOptions(),
 	}
 }
 
@@ -44,6 +77,15 @@ func NewMySQLOptions() *MySQLOptions {
 func (o *MySQLOptions) Validate() []error {
 	errs := []error{}
 
+	if err := validateReplicaPolicy(o.ReplicaPolicy); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateConnect(o.ConnectMaxWait, o.ConnectJitter)...)
+	errs = append(errs, validateSessionTimeouts(o.StatementTimeout, o.LockTimeout)...)
+	if o.TLSOptions != nil {
+		errs = append(errs, o.TLSOptions.Validate()...)
+	}
+
 	return errs
 }
 
@@ -64,22 +106,54 @@ func (o *MySQLOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
 		"Maximum connection life time allowed to connect to mysql.")
 	fs.IntVar(&o.LogLevel, join(prefixes...)+"mysql.log-mode", o.LogLevel, ""+
 		"Specify gorm log level.")
+	fs.StringSliceVar(&o.Replicas, join(prefixes...)+"mysql.replicas", o.Replicas, ""+
+		"Addresses of MySQL read replicas. Queries are spread across healthy replicas, writes go to the primary.")
+	fs.StringVar(&o.ReplicaPolicy, join(prefixes...)+"mysql.replica-policy", o.ReplicaPolicy, ""+
+		"Load-balancing policy across MySQL read replicas, round-robin or random.")
+	fs.DurationVar(&o.ReplicaHealthCheckInterval, join(prefixes...)+"mysql.replica-health-check-interval", o.ReplicaHealthCheckInterval, ""+
+		"Interval of MySQL read replica health checks, 0 disables them.")
+	fs.DurationVar(&o.ConnectMaxWait, join(prefixes...)+"mysql.connect-max-wait", o.ConnectMaxWait, ""+
+		"How long to retry connecting while MySQL is not up yet, with exponential backoff. 0 fails at once.")
+	fs.Float64Var(&o.ConnectJitter, join(prefixes...)+"mysql.connect-jitter", o.ConnectJitter, ""+
+		"Fraction by which the waits between MySQL connection attempts are randomized.")
+	fs.StringVar(&o.Charset, join(prefixes...)+"mysql.charset", o.Charset, "Character set of the MySQL connections.")
+	fs.StringVar(&o.Collation, join(prefixes...)+"mysql.collation", o.Collation, ""+
+		"Collation of the MySQL connections, e.g. utf8mb4_unicode_ci. Empty for the default collation of the charset.")
+	fs.StringVar(&o.TimeZone, join(prefixes...)+"mysql.timezone", o.TimeZone, ""+
+		"Time zone of parsed MySQL DATETIME and TIMESTAMP values, an IANA time zone name or Local.")
+	fs.DurationVar(&o.StatementTimeout, join(prefixes...)+"mysql.statement-timeout", o.StatementTimeout, ""+
+		"Abort MySQL SELECT statements running longer, 0 to disable.")
+	fs.DurationVar(&o.LockTimeout, join(prefixes...)+"mysql.lock-timeout", o.LockTimeout, ""+
+		"How long MySQL statements wait for a row lock, rounded up to seconds. 0 keeps the server default.")
+	fs.StringVar(&o.ApplicationName, join(prefixes...)+"mysql.application-name", o.ApplicationName, ""+
+		"Program name reported in the MySQL connection attributes.")
+	fs.StringToStringVar(&o.Params, join(prefixes...)+"mysql.params", o.Params, ""+
+		"Extra MySQL DSN parameters, e.g. driver settings or session system variables.")
+	o.TLSOptions.AddFlags(fs, append(prefixes, "mysql")...)
 }
 
-// DSN return DSN from MySQLOptions.
+// DSN return DSN from MySQLOptions. The TLS options are not part of it.
 func (o *MySQLOptions) DSN() string {
-	return fmt.Sprintf(`%s:%s@tcp(%s)/%s?charset=utf8&parseTime=%t&loc=%s`,
-		o.Username,
-		o.Password,
-		o.Addr,
-		o.Database,
-		true,
-		"Local")
+	return o.gormxOptions().DSN()
 }
 
 // NewDB create mysql store with the given config.
 func (o *MySQLOptions) NewDB() (*gorm.DB, error) {
-	opts := &gormx.MySQLOptions{
+	opts := o.gormxOptions()
+	if o.TLSOptions != nil {
+		tlsConfig, err := o.TLSOptions.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	return gormx.NewMySQL(opts)
+}
+
+// gormxOptions converts the options to gormx options, without TLS config.
+func (o *MySQLOptions) gormxOptions() *gormx.MySQLOptions {
+	return &gormx.MySQLOptions{
 		Addr:                  o.Addr,
 		Username:              o.Username,
 		Password:              o.Password,
@@ -87,8 +161,37 @@ func (o *MySQLOptions) NewDB() (*gorm.DB, error) {
 		MaxIdleConnections:    o.MaxIdleConnections,
 		MaxOpenConnections:    o.MaxOpenConnections,
 		MaxConnectionLifeTime: o.MaxConnectionLifeTime,
+		Charset:               o.Charset,
+		Collation:             o.Collation,
+		TimeZone:              o.TimeZone,
+		StatementTimeout:      o.StatementTimeout,
+		LockTimeout:           o.LockTimeout,
+		ApplicationName:       o.ApplicationName,
+		Params:                o.Params,
+		Connect:               connectBackoff(o.ConnectMaxWait, o.ConnectJitter),
 		Logger:                log.Default().LogMode(gormlogger.LogLevel(o.LogLevel)),
 	}
+}
 
-	return gormx.NewMySQL(opts)
+// NewDBProvider creates a store.DBProvider that sends writes and transactions to the
+// primary and spreads queries across the healthy replicas.
+func (o *MySQLOptions) NewDBProvider() (*store.ReadWriteProvider, error) {
+	primary, err := o.NewDB()
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]*gorm.DB, 0, len(o.Replicas))
+	for _, addr := range o.Replicas {
+		replicaOpts := *o
+		replicaOpts.Addr = addr
+		db, err := replicaOpts.NewDB()
+		if err != nil {
+			closeDBs(append(replicas, primary)...)
+			return nil, fmt.Errorf("failed to connect to replica %s: %w", addr, err)
+		}
+		replicas = append(replicas, db)
+	}
+
+	return newReadWriteProvider(primary, replicas, o.ReplicaPolicy, o.ReplicaHealthCheckInterval)
 }
@@ -1,6 +1,9 @@
 package options
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -9,6 +12,7 @@ import (
 
 	"github.com/moweilong/mo/gormx"
 	"github.com/moweilong/mo/log"
+	"github.com/moweilong/mo/store"
 )
 
 var _ IOptions = (*PostgreSQLOptions)(nil)
@@ -23,19 +27,52 @@ type PostgreSQLOptions struct {
 	MaxOpenConnections    int           `json:"max-open-connections,omitempty" mapstructure:"max-open-connections"`
 	MaxConnectionLifeTime time.Duration `json:"max-connection-life-time,omitempty" mapstructure:"max-connection-life-time"`
 	LogLevel              int           `json:"log-level" mapstructure:"log-level"`
+	// Replicas are the addresses of read replicas, sharing the credentials and database of the primary.
+	Replicas []string `json:"replicas,omitempty" mapstructure:"replicas"`
+	// ReplicaPolicy is the load-balancing policy across replicas, "round-robin" or "random".
+	ReplicaPolicy string `json:"replica-policy,omitempty" mapstructure:"replica-policy"`
+	// ReplicaHealthCheckInterval is how often replicas are pinged; unreachable ones are ejected until they recover.
+	ReplicaHealthCheckInterval time.Duration `json:"replica-health-check-interval,omitempty" mapstructure:"replica-health-check-interval"`
+	// ConnectMaxWait is how long connecting is retried while the database is not up yet, 0 to fail at once.
+	ConnectMaxWait time.Duration `json:"connect-max-wait,omitempty" mapstructure:"connect-max-wait"`
+	// ConnectJitter randomizes the waits between connection attempts by up to this fraction.
+	ConnectJitter float64 `json:"connect-jitter,omitempty" mapstructure:"connect-jitter"`
+	// SSLMode is the libpq sslmode: disable, allow, prefer, require, verify-ca or verify-full.
+	// Empty derives it from TLSOptions: disable without TLS, verify-full with a CA certificate,
+	// require otherwise.
+	SSLMode string `json:"sslmode,omitempty" mapstructure:"sslmode"`
+	// TimeZone is the time zone of the sessions.
+	TimeZone string `json:"timezone,omitempty" mapstructure:"timezone"`
+	// SearchPath is the schema search path of the sessions, e.g. "tenant,public".
+	SearchPath string `json:"search-path,omitempty" mapstructure:"search-path"`
+	// StatementTimeout aborts statements running longer, 0 to disable.
+	StatementTimeout time.Duration `json:"statement-timeout,omitempty" mapstructure:"statement-timeout"`
+	// LockTimeout aborts statements waiting longer for a lock, 0 to disable.
+	LockTimeout time.Duration `json:"lock-timeout,omitempty" mapstructure:"lock-timeout"`
+	// ApplicationName identifies the connections in pg_stat_activity.
+	ApplicationName string `json:"application-name,omitempty" mapstructure:"application-name"`
+	// Params are extra DSN parameters, taking precedence over the other options.
+	Params map[string]string `json:"params,omitempty" mapstructure:"params"`
+	// TLSOptions configures TLS, with a CA certificate verifying the server and a client certificate.
+	TLSOptions *TLSOptions `json:"tls" mapstructure:"tls"`
 }
 
 // NewPostgreSQLOptions create a `zero` value instance.
 func NewPostgreSQLOptions() *PostgreSQLOptions {
 	return &PostgreSQLOptions{
-		Addr:                  "127.0.0.1:5432",
-		Username:              "onex",
-		Password:              "onex(#)666",
-		Database:              "onex",
-		MaxIdleConnections:    100,
-		MaxOpenConnections:    100,
-		MaxConnectionLifeTime: time.Duration(10) * time.Second,
-		LogLevel:              1, // Silent
+		Addr:                       "127.0.0.1:5432",
+		Username:                   "onex",
+		Password:                   "onex(#)666",
+		Database:                   "onex",
+		MaxIdleConnections:         100,
+		MaxOpenConnections:         100,
+		MaxConnectionLifeTime:      time.Duration(10) * time.Second,
+		LogLevel:                   1, // Silent
+		ReplicaPolicy:              ReplicaPolicyRoundRobin,
+		ReplicaHealthCheckInterval: 10 * time.Second,
+		ConnectJitter:              defaultConnectJitter,
+		TimeZone:                   "Asia/Shanghai",
+		TLSOptions:                 NewTLSOptions(),
 	}
 }
 
@@ -43,6 +80,18 @@ func NewPostgreSQLOptions() *PostgreSQLOptions {
 func (o *PostgreSQLOptions) Validate() []error {
 	errs := []error{}
 
+	if err := validateReplicaPolicy(o.ReplicaPolicy); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateConnect(o.ConnectMaxWait, o.ConnectJitter)...)
+	errs = append(errs, validateSessionTimeouts(o.StatementTimeout, o.LockTimeout)...)
+	if o.TLSOptions != nil {
+		errs = append(errs, o.TLSOptions.Validate()...)
+	}
+	if o.SSLMode != "" && !slices.Contains(postgresSSLModes, o.SSLMode) {
+		errs = append(errs, fmt.Errorf("unknown sslmode %q, must be one of %s", o.SSLMode, strings.Join(postgresSSLModes, ", ")))
+	}
+
 	return errs
 }
 
@@ -63,6 +112,31 @@ func (o *PostgreSQLOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
 		"Maximum connection life time allowed to connect to postgresql.")
 	fs.IntVar(&o.LogLevel, join(prefixes...)+"postgresql.log-mode", o.LogLevel, ""+
 		"Specify gorm log level.")
+	fs.StringSliceVar(&o.Replicas, join(prefixes...)+"postgresql.replicas", o.Replicas, ""+
+		"Addresses of PostgreSQL read replicas. Queries are spread across healthy replicas, writes go to the primary.")
+	fs.StringVar(&o.ReplicaPolicy, join(prefixes...)+"postgresql.replica-policy", o.ReplicaPolicy, ""+
+		"Load-balancing policy across PostgreSQL read replicas, round-robin or random.")
+	fs.DurationVar(&o.ReplicaHealthCheckInterval, join(prefixes...)+"postgresql.replica-health-check-interval", o.ReplicaHealthCheckInterval, ""+
+		"Interval of PostgreSQL read replica health checks, 0 disables them.")
+	fs.DurationVar(&o.ConnectMaxWait, join(prefixes...)+"postgresql.connect-max-wait", o.ConnectMaxWait, ""+
+		"How long to retry connecting while PostgreSQL is not up yet, with exponential backoff. 0 fails at once.")
+	fs.Float64Var(&o.ConnectJitter, join(prefixes...)+"postgresql.connect-jitter", o.ConnectJitter, ""+
+		"Fraction by which the waits between PostgreSQL connection attempts are randomized.")
+	fs.StringVar(&o.SSLMode, join(prefixes...)+"postgresql.sslmode", o.SSLMode, ""+
+		"PostgreSQL sslmode: disable, allow, prefer, require, verify-ca or verify-full. "+
+		"Empty derives it from the tls options: disable without TLS, verify-full with a CA certificate, require otherwise.")
+	fs.StringVar(&o.TimeZone, join(prefixes...)+"postgresql.timezone", o.TimeZone, "Time zone of the PostgreSQL sessions.")
+	fs.StringVar(&o.SearchPath, join(prefixes...)+"postgresql.search-path", o.SearchPath, ""+
+		"Schema search path of the PostgreSQL sessions, e.g. tenant,public.")
+	fs.DurationVar(&o.StatementTimeout, join(prefixes...)+"postgresql.statement-timeout", o.StatementTimeout, ""+
+		"Abort PostgreSQL statements running longer, 0 to disable.")
+	fs.DurationVar(&o.LockTimeout, join(prefixes...)+"postgresql.lock-timeout", o.LockTimeout, ""+
+		"Abort PostgreSQL statements waiting longer for a lock, 0 to disable.")
+	fs.StringVar(&o.ApplicationName, join(prefixes...)+"postgresql.application-name", o.ApplicationName, ""+
+		"Application name of the PostgreSQL connections, shown in pg_stat_activity.")
+	fs.StringToStringVar(&o.Params, join(prefixes...)+"postgresql.params", o.Params, ""+
+		"Extra PostgreSQL DSN parameters, e.g. connection settings or run-time parameters.")
+	o.TLSOptions.AddFlags(fs, append(prefixes, "postgresql")...)
 }
 
 // NewDB create postgresql store with the given config.
@@ -75,8 +149,66 @@ func (o *PostgreSQLOptions) NewDB() (*gorm.DB, error) {
 		MaxIdleConnections:    o.MaxIdleConnections,
 		MaxOpenConnections:    o.MaxOpenConnections,
 		MaxConnectionLifeTime: o.MaxConnectionLifeTime,
+		SSLMode:               o.sslMode(),
+		TimeZone:              o.TimeZone,
+		SearchPath:            o.SearchPath,
+		StatementTimeout:      o.StatementTimeout,
+		LockTimeout:           o.LockTimeout,
+		ApplicationName:       o.ApplicationName,
+		Params:                o.Params,
+		Connect:               connectBackoff(o.ConnectMaxWait, o.ConnectJitter),
 		Logger:                log.Default().LogMode(gormlogger.LogLevel(o.LogLevel)),
 	}
+	if o.useTLS() {
+		opts.SSLRootCert = o.TLSOptions.CaCert
+		opts.SSLCert = o.TLSOptions.Cert
+		opts.SSLKey = o.TLSOptions.Key
+	}
 
 	return gormx.NewPostgreSQL(opts)
 }
+
+// useTLS reports whether the TLS options enable TLS.
+func (o *PostgreSQLOptions) useTLS() bool {
+	return o.TLSOptions != nil && o.TLSOptions.UseTLS
+}
+
+// postgresSSLModes are the sslmode values accepted by PostgreSQL.
+var postgresSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// sslMode returns the sslmode of the connections, derived from the TLS options unless set.
+func (o *PostgreSQLOptions) sslMode() string {
+	switch {
+	case o.SSLMode != "":
+		return o.SSLMode
+	case !o.useTLS():
+		return "disable"
+	case o.TLSOptions.CaCert != "" && !o.TLSOptions.InsecureSkipVerify:
+		return "verify-full"
+	default:
+		return "require"
+	}
+}
+
+// NewDBProvider creates a store.DBProvider that sends writes and transactions to the
+// primary and spreads queries across the healthy replicas.
+func (o *PostgreSQLOptions) NewDBProvider() (*store.ReadWriteProvider, error) {
+	primary, err := o.NewDB()
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]*gorm.DB, 0, len(o.Replicas))
+	for _, addr := range o.Replicas {
+		replicaOpts := *o
+		replicaOpts.Addr = addr
+		db, err := replicaOpts.NewDB()
+		if err != nil {
+			closeDBs(append(replicas, primary)...)
+			return nil, fmt.Errorf("failed to connect to replica %s: %w", addr, err)
+		}
+		replicas = append(replicas, db)
+	}
+
+	return newReadWriteProvider(primary, replicas, o.ReplicaPolicy, o.ReplicaHealthCheckInterval)
+}
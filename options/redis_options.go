@@ -27,22 +27,27 @@ type RedisOptions struct {
 	PoolSize     int           `json:"pool-size" mapstructure:"pool-size"`
 	// tracing switch
 	EnableTrace bool `json:"enable-trace" mapstructure:"enable-trace"`
+	// ConnectMaxWait is how long connecting is retried while Redis is not up yet, 0 to fail at once.
+	ConnectMaxWait time.Duration `json:"connect-max-wait" mapstructure:"connect-max-wait"`
+	// ConnectJitter randomizes the waits between connection attempts by up to this fraction.
+	ConnectJitter float64 `json:"connect-jitter" mapstructure:"connect-jitter"`
 }
 
 // NewRedisOptions create a `zero` value instance.
 func NewRedisOptions() *RedisOptions {
 	return &RedisOptions{
-		Addr:         "127.0.0.1:6379",
-		Username:     "",
-		Password:     "",
-		Database:     0,
-		MaxRetries:   3,
-		MinIdleConns: 0,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolSize:     10,
-		EnableTrace:  false,
+		Addr:          "127.0.0.1:6379",
+		Username:      "",
+		Password:      "",
+		Database:      0,
+		MaxRetries:    3,
+		MinIdleConns:  0,
+		DialTimeout:   5 * time.Second,
+		ReadTimeout:   3 * time.Second,
+		WriteTimeout:  3 * time.Second,
+		PoolSize:      10,
+		EnableTrace:   false,
+		ConnectJitter: defaultConnectJitter,
 	}
 }
 
@@ -57,6 +62,7 @@ func (o *RedisOptions) Validate() []error {
 	if o.PoolTimeout == 0 {
 		o.PoolTimeout = o.ReadTimeout + 1*time.Second
 	}
+	errs = append(errs, validateConnect(o.ConnectMaxWait, o.ConnectJitter)...)
 
 	return errs
 }
@@ -77,6 +83,10 @@ func (o *RedisOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
 		"Amount of time client waits for connection if all connections are busy before returning an error.")
 	fs.IntVar(&o.PoolSize, "redis.pool-size", o.PoolSize, "Maximum number of socket connections.")
 	fs.BoolVar(&o.EnableTrace, "redis.enable-trace", o.EnableTrace, "Redis hook tracing (using open telemetry).")
+	fs.DurationVar(&o.ConnectMaxWait, "redis.connect-max-wait", o.ConnectMaxWait, ""+
+		"How long to retry connecting while Redis is not up yet, with exponential backoff. 0 fails at once.")
+	fs.Float64Var(&o.ConnectJitter, "redis.connect-jitter", o.ConnectJitter, ""+
+		"Fraction by which the waits between Redis connection attempts are randomized.")
 }
 
 func (o *RedisOptions) NewClient() (*redis.Client, error) {
@@ -92,6 +102,7 @@ func (o *RedisOptions) NewClient() (*redis.Client, error) {
 		WriteTimeout: o.WriteTimeout,
 		PoolSize:     o.PoolSize,
 		PoolTimeout:  o.PoolTimeout,
+		Connect:      connectBackoff(o.ConnectMaxWait, o.ConnectJitter),
 	}
 
 	rdb, err := cache.NewRedis(opts)
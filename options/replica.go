@@ -0,0 +1,49 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store"
+)
+
+// Replica load-balancing policies accepted by the replica-policy options.
+const (
+	ReplicaPolicyRoundRobin = "round-robin"
+	ReplicaPolicyRandom     = "random"
+)
+
+// validateReplicaPolicy verifies that policy names a known replica load-balancing policy.
+func validateReplicaPolicy(policy string) error {
+	switch policy {
+	case "", ReplicaPolicyRoundRobin, ReplicaPolicyRandom:
+		return nil
+	}
+	return fmt.Errorf("unknown replica policy %q, must be %q or %q", policy, ReplicaPolicyRoundRobin, ReplicaPolicyRandom)
+}
+
+// newReadWriteProvider creates a store.ReadWriteProvider over the primary and replicas,
+// closing them if it fails.
+func newReadWriteProvider(primary *gorm.DB, replicas []*gorm.DB, policy string, healthCheckInterval time.Duration) (*store.ReadWriteProvider, error) {
+	if err := validateReplicaPolicy(policy); err != nil {
+		closeDBs(append(replicas, primary)...)
+		return nil, err
+	}
+
+	opts := []store.ReadWriteOption{store.WithHealthCheck(healthCheckInterval, 2*time.Second)}
+	if policy == ReplicaPolicyRandom {
+		opts = append(opts, store.WithReplicaPolicy(store.RandomPolicy()))
+	}
+	return store.NewReadWriteProvider(primary, replicas, opts...), nil
+}
+
+// closeDBs closes the connection pools of dbs, for databases opened before a failure.
+func closeDBs(dbs ...*gorm.DB) {
+	for _, db := range dbs {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package options
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/pflag"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/gormx"
+	"github.com/moweilong/mo/log"
+	"github.com/moweilong/mo/store"
+)
+
+var _ IOptions = (*SQLiteOptions)(nil)
+
+// SQLiteOptions defines options for sqlite database, meant for local development and tests.
+type SQLiteOptions struct {
+	// Path is the database file, or ":memory:" for an in-memory database.
+	Path                  string        `json:"path,omitempty" mapstructure:"path"`
+	MaxIdleConnections    int           `json:"max-idle-connections,omitempty" mapstructure:"max-idle-connections,omitempty"`
+	MaxOpenConnections    int           `json:"max-open-connections,omitempty" mapstructure:"max-open-connections"`
+	MaxConnectionLifeTime time.Duration `json:"max-connection-life-time,omitempty" mapstructure:"max-connection-life-time"`
+	// BusyTimeout is how long a connection waits for a lock held by another one.
+	BusyTimeout time.Duration `json:"busy-timeout,omitempty" mapstructure:"busy-timeout"`
+	LogLevel    int           `json:"log-level" mapstructure:"log-level"`
+}
+
+// NewSQLiteOptions create a `zero` value instance.
+func NewSQLiteOptions() *SQLiteOptions {
+	return &SQLiteOptions{
+		Path:               gormx.SQLiteMemory,
+		MaxIdleConnections: 10,
+		MaxOpenConnections: 10,
+		BusyTimeout:        5 * time.Second,
+		LogLevel:           1, // Silent
+	}
+}
+
+// Validate verifies flags passed to SQLiteOptions.
+func (o *SQLiteOptions) Validate() []error {
+	errs := []error{}
+
+	if o.Path == "" {
+		errs = append(errs, errors.New("sqlite path must not be empty"))
+	}
+
+	return errs
+}
+
+// AddFlags adds flags related to sqlite storage for a specific APIServer to the specified FlagSet.
+func (o *SQLiteOptions) AddFlags(fs *pflag.FlagSet, prefixes ...string) {
+	fs.StringVar(&o.Path, join(prefixes...)+"sqlite.path", o.Path, ""+
+		"SQLite database file, created if missing, or :memory: for an in-memory database.")
+	fs.IntVar(&o.MaxIdleConnections, join(prefixes...)+"sqlite.max-idle-connections", o.MaxIdleConnections, ""+
+		"Maximum idle connections allowed to connect to sqlite. Ignored for in-memory databases.")
+	fs.IntVar(&o.MaxOpenConnections, join(prefixes...)+"sqlite.max-open-connections", o.MaxOpenConnections, ""+
+		"Maximum open connections allowed to connect to sqlite. Ignored for in-memory databases.")
+	fs.DurationVar(&o.MaxConnectionLifeTime, join(prefixes...)+"sqlite.max-connection-life-time", o.MaxConnectionLifeTime, ""+
+		"Maximum connection life time allowed to connect to sqlite, 0 for no limit.")
+	fs.DurationVar(&o.BusyTimeout, join(prefixes...)+"sqlite.busy-timeout", o.BusyTimeout, ""+
+		"How long to wait for a sqlite database lock held by another connection.")
+	fs.IntVar(&o.LogLevel, join(prefixes...)+"sqlite.log-mode", o.LogLevel, ""+
+		"Specify gorm log level.")
+}
+
+// NewDB create sqlite store with the given config.
+func (o *SQLiteOptions) NewDB() (*gorm.DB, error) {
+	opts := &gormx.SQLiteOptions{
+		Path:                  o.Path,
+		MaxIdleConnections:    o.MaxIdleConnections,
+		MaxOpenConnections:    o.MaxOpenConnections,
+		MaxConnectionLifeTime: o.MaxConnectionLifeTime,
+		BusyTimeout:           o.BusyTimeout,
+		Logger:                log.Default().LogMode(gormlogger.LogLevel(o.LogLevel)),
+	}
+
+	return gormx.NewSQLite(opts)
+}
+
+// NewDBProvider creates a store.DBProvider over the database, so that it can stand in for
+// the MySQL and PostgreSQL providers.
+func (o *SQLiteOptions) NewDBProvider() (*store.ReadWriteProvider, error) {
+	db, err := o.NewDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return newReadWriteProvider(db, nil, ReplicaPolicyRoundRobin, 0)
+}
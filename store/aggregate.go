@@ -0,0 +1,286 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrInvalidColumn is returned when a projection or aggregate references an unknown column
+// or an invalid alias.
+var ErrInvalidColumn = errors.New("invalid column")
+
+// AggregateFunc is an SQL aggregate function.
+type AggregateFunc string
+
+// Aggregate functions supported by Store.Aggregate.
+const (
+	AggregateCount AggregateFunc = "COUNT"
+	AggregateSum   AggregateFunc = "SUM"
+	AggregateAvg   AggregateFunc = "AVG"
+	AggregateMin   AggregateFunc = "MIN"
+	AggregateMax   AggregateFunc = "MAX"
+)
+
+// aliasPattern matches the aliases accepted for aggregates.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Aggregation is an aggregate column of Store.Aggregate.
+type Aggregation struct {
+	// Func is the aggregate function.
+	Func AggregateFunc
+	// Column is the column or field name aggregated. It is empty for COUNT(*).
+	Column string
+	// As is the alias of the result, matched against the column names of the destination.
+	// Defaults to the lower-case function and column, e.g. "sum_amount".
+	As string
+}
+
+// Count returns an Aggregation counting the rows, or the non-null values of column when set.
+func Count(column, as string) Aggregation {
+	return Aggregation{Func: AggregateCount, Column: column, As: as}
+}
+
+// Sum returns an Aggregation summing column.
+func Sum(column, as string) Aggregation {
+	return Aggregation{Func: AggregateSum, Column: column, As: as}
+}
+
+// Avg returns an Aggregation averaging column.
+func Avg(column, as string) Aggregation {
+	return Aggregation{Func: AggregateAvg, Column: column, As: as}
+}
+
+// Min returns an Aggregation selecting the minimum of column.
+func Min(column, as string) Aggregation {
+	return Aggregation{Func: AggregateMin, Column: column, As: as}
+}
+
+// Max returns an Aggregation selecting the maximum of column.
+func Max(column, as string) Aggregation {
+	return Aggregation{Func: AggregateMax, Column: column, As: as}
+}
+
+// Count returns the number of objects matching the provided where options, ignoring their pagination.
+func (s *Store[T]) Count(ctx context.Context, opts *where.Options) (int64, error) {
+	var count int64
+	err := s.readShards(ctx, opts, func(ctx context.Context) error {
+		sch, err := s.schema(ctx)
+		if err != nil {
+			return err
+		}
+		var shardCount int64
+		err = s.count(ctx, sch, opts, &shardCount)
+		count += shardCount
+		return err
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to count objects in database", "conditions", opts)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Exists reports whether any object matches the provided where options.
+func (s *Store[T]) Exists(ctx context.Context, opts *where.Options) (bool, error) {
+	var found []int
+	err := s.readShards(ctx, opts, func(ctx context.Context) error {
+		if len(found) > 0 {
+			return nil
+		}
+		sch, err := s.schema(ctx)
+		if err != nil {
+			return err
+		}
+		db, err := s.withJoins(sch, s.readDB(ctx, opts), opts)
+		if err != nil {
+			return err
+		}
+		// An explicit select clause keeps GORM from selecting the columns of joined tables.
+		db = db.Clauses(clause.Select{Expression: clause.Expr{SQL: "1"}})
+		return db.Model(new(T)).Offset(-1).Limit(1).Find(&found).Error
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to check existence of object in database", "conditions", opts)
+		return false, err
+	}
+	return len(found) > 0, nil
+}
+
+// Pluck retrieves the values of a single column of the objects matching the provided where
+// options into dest, a pointer to a slice, e.g. *[]string. The column can be given as column
+// or field name and objects are ordered by the sort specifications of opts, if any.
+func (s *Store[T]) Pluck(ctx context.Context, opts *where.Options, column string, dest any) error {
+	err := s.onShard(ctx, opts, func(ctx context.Context) error {
+		return s.pluck(ctx, opts, column, dest)
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to pluck column from database", "conditions", opts, "column", column)
+		return err
+	}
+	return nil
+}
+
+// pluck implements Pluck.
+func (s *Store[T]) pluck(ctx context.Context, opts *where.Options, column string, dest any) error {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	name, err := columnName(sch, column)
+	if err != nil {
+		return err
+	}
+
+	db, err := s.withJoins(sch, s.readDB(ctx, opts), opts)
+	if err != nil {
+		return err
+	}
+	if len(opts.Sorts) > 0 {
+		columns, err := s.orderColumns(sch, opts.Sorts)
+		if err != nil {
+			return err
+		}
+		db = db.Clauses(orderBy(columns, false))
+	}
+	// The column is qualified so that it stays unambiguous next to joined tables.
+	db = db.Clauses(clause.Select{Columns: []clause.Column{{Table: clause.CurrentTable, Name: name}}})
+	return db.Model(new(T)).Pluck(name, dest).Error
+}
+
+// Aggregate computes aggregates over the objects matching the provided where options into
+// dest, a pointer to a struct or to a slice of structs whose fields match the group-by
+// columns and aggregate aliases. Rows are grouped by opts.GroupBy and groups filtered by
+// opts.Having; pagination and sort specifications apply to the groups, and may sort by
+// aggregate alias, e.g. S("-total").
+//
+//	var rows []struct {
+//		Status string
+//		Total  float64
+//	}
+//	err := store.Aggregate(ctx, where.G("status").H("total > ?", 100).S("-total"), &rows, Sum("amount", "total"))
+func (s *Store[T]) Aggregate(ctx context.Context, opts *where.Options, dest any, aggs ...Aggregation) error {
+	err := s.onShard(ctx, opts, func(ctx context.Context) error {
+		return s.aggregate(ctx, opts, dest, aggs...)
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to aggregate objects in database", "conditions", opts)
+		return err
+	}
+	return nil
+}
+
+// aggregate implements Aggregate.
+func (s *Store[T]) aggregate(ctx context.Context, opts *where.Options, dest any, aggs ...Aggregation) error {
+	if len(aggs) == 0 {
+		return errors.New("no aggregation given")
+	}
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+
+	selects := make([]clause.Expression, 0, len(opts.GroupBy)+len(aggs))
+	groupBy := clause.GroupBy{}
+	for _, col := range opts.GroupBy {
+		name, err := columnName(sch, col)
+		if err != nil {
+			return err
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: name}
+		selects = append(selects, clause.Expr{SQL: "?", Vars: []any{column}})
+		groupBy.Columns = append(groupBy.Columns, column)
+	}
+
+	aliases := make(map[string]struct{}, len(aggs))
+	for _, agg := range aggs {
+		expr, alias, err := aggregateExpr(sch, agg)
+		if err != nil {
+			return err
+		}
+		selects = append(selects, expr)
+		aliases[alias] = struct{}{}
+	}
+
+	db, err := s.withJoins(sch, s.readDB(ctx, opts), opts)
+	if err != nil {
+		return err
+	}
+	db = db.Model(new(T)).Clauses(clause.Select{Expression: clause.CommaExpression{Exprs: selects}})
+	for _, having := range opts.Having {
+		groupBy.Having = append(groupBy.Having, db.Statement.BuildCondition(having.Query, having.Args...)...)
+	}
+	if len(groupBy.Columns) > 0 || len(groupBy.Having) > 0 {
+		db = db.Clauses(groupBy)
+	}
+
+	order := clause.OrderBy{}
+	for _, srt := range opts.Sorts {
+		column := clause.Column{Name: srt.Column}
+		if _, ok := aliases[srt.Column]; !ok {
+			field := sch.LookUpField(srt.Column)
+			if field == nil || field.DBName == "" || !s.isSortable(srt.Column, field) {
+				return fmt.Errorf("%w: %s", ErrInvalidSort, srt.Column)
+			}
+			column = clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		}
+		order.Columns = append(order.Columns, clause.OrderByColumn{Column: column, Desc: srt.Desc})
+	}
+	if len(order.Columns) > 0 {
+		db = db.Clauses(order)
+	}
+
+	return db.Scan(dest).Error
+}
+
+// aggregateExpr builds the select expression of agg and returns it with its alias.
+func aggregateExpr(sch *schema.Schema, agg Aggregation) (clause.Expression, string, error) {
+	switch agg.Func {
+	case AggregateCount, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+	default:
+		return nil, "", fmt.Errorf("unknown aggregate function %q", agg.Func)
+	}
+
+	alias := agg.As
+	if alias == "" {
+		alias = strings.ToLower(string(agg.Func))
+		if agg.Column != "" {
+			alias += "_" + agg.Column
+		}
+	}
+	if !aliasPattern.MatchString(alias) {
+		return nil, "", fmt.Errorf("%w: alias %q", ErrInvalidColumn, alias)
+	}
+
+	if agg.Column == "" {
+		if agg.Func != AggregateCount {
+			return nil, "", fmt.Errorf("%w: %s requires a column", ErrInvalidColumn, agg.Func)
+		}
+		return clause.Expr{SQL: "COUNT(*) AS ?", Vars: []any{clause.Column{Name: alias}}}, alias, nil
+	}
+
+	name, err := columnName(sch, agg.Column)
+	if err != nil {
+		return nil, "", err
+	}
+	return clause.Expr{
+		SQL:  string(agg.Func) + "(?) AS ?",
+		Vars: []any{clause.Column{Table: clause.CurrentTable, Name: name}, clause.Column{Name: alias}},
+	}, alias, nil
+}
+
+// columnName resolves a column or field name of the model to its column name.
+func columnName(sch *schema.Schema, name string) (string, error) {
+	field := sch.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+	}
+	return field.DBName, nil
+}
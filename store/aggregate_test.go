@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+type testCompany struct {
+	ID        uint           `gorm:"primaryKey"`
+	Name      string         `gorm:"size:64"`
+	Employees []testEmployee `gorm:"foreignKey:CompanyID"`
+}
+
+type testEmployee struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"size:64"`
+	Age       int
+	Salary    int
+	CompanyID uint
+	Company   *testCompany
+}
+
+// newEmployeeStore creates a Store of testEmployee over a fresh database with two companies:
+// acme employing alice and bob, and initech employing carol.
+func newEmployeeStore(t *testing.T) (*Store[testEmployee], *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t, &testCompany{}, &testEmployee{})
+	require.NoError(t, db.Create([]*testCompany{{ID: 1, Name: "acme"}, {ID: 2, Name: "initech"}}).Error)
+	require.NoError(t, db.Create([]*testEmployee{
+		{Name: "alice", Age: 30, Salary: 100, CompanyID: 1},
+		{Name: "bob", Age: 40, Salary: 250, CompanyID: 1},
+		{Name: "carol", Age: 50, Salary: 300, CompanyID: 2},
+	}).Error)
+	return NewStore[testEmployee](NewReadWriteProvider(db, nil), nil, WithTenantExemption[testEmployee]()), db
+}
+
+func TestStorePluck(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newEmployeeStore(t)
+
+	var names []string
+	require.NoError(t, s.Pluck(ctx, where.S("-age"), "Name", &names))
+	assert.Equal(t, []string{"carol", "bob", "alice"}, names)
+
+	names = nil
+	require.NoError(t, s.Pluck(ctx, where.F("company_id", 1).S("name"), "name", &names))
+	assert.Equal(t, []string{"alice", "bob"}, names)
+
+	var ages []int
+	assert.ErrorIs(t, s.Pluck(ctx, where.NewWhere(), "unknown", &ages), ErrInvalidColumn)
+
+	exists, err := s.Exists(ctx, where.F("company_id", 2))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = s.Exists(ctx, where.F("company_id", 2).F("age", 30))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Conditions may reference the columns of joined associations, even ambiguous ones.
+	names = nil
+	require.NoError(t, s.Pluck(ctx, where.NewWhere().Q("Company.name = ?", "acme").Joins("Company").S("name"), "name", &names))
+	assert.Equal(t, []string{"alice", "bob"}, names)
+	exists, err = s.Exists(ctx, where.NewWhere().Q("Company.name = ?", "initech").Joins("Company"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = s.Exists(ctx, where.NewWhere().Q("Company.name = ?", "initech").Joins("Company").F("age", 30))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	count, err := s.Count(ctx, where.F("company_id", 1).L(1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "pagination is ignored")
+	count, err = s.Count(ctx, where.NewWhere().Q("Company.name = ?", "acme").Joins("Company"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestStoreAggregate(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newEmployeeStore(t)
+
+	var total struct {
+		Count int64
+		Total int
+		Avg   float64
+	}
+	require.NoError(t, s.Aggregate(ctx, where.NewWhere(), &total,
+		Count("", "count"), Sum("salary", "total"), Avg("age", "avg")))
+	assert.Equal(t, int64(3), total.Count)
+	assert.Equal(t, 650, total.Total)
+	assert.InDelta(t, 40, total.Avg, 0.001)
+
+	var groups []struct {
+		CompanyID uint
+		Total     int
+		MaxAge    int
+	}
+	require.NoError(t, s.Aggregate(ctx, where.G("company_id").H("total > ?", 100).S("-total"), &groups,
+		Sum("salary", "total"), Max("age", "max_age")))
+	require.Len(t, groups, 2)
+	assert.Equal(t, uint(1), groups[0].CompanyID)
+	assert.Equal(t, 350, groups[0].Total)
+	assert.Equal(t, 40, groups[0].MaxAge)
+	assert.Equal(t, uint(2), groups[1].CompanyID)
+	assert.Equal(t, 300, groups[1].Total)
+	assert.Equal(t, 50, groups[1].MaxAge)
+
+	total.Total = 0
+	require.NoError(t, s.Aggregate(ctx, where.F("company_id", 1), &total, Sum("salary", "total")))
+	assert.Equal(t, 350, total.Total)
+
+	// Aggregates are restricted by conditions on joined associations like other queries.
+	total.Total = 0
+	require.NoError(t, s.Aggregate(ctx, where.NewWhere().Q("Company.name = ?", "initech").Joins("Company"), &total, Sum("salary", "total")))
+	assert.Equal(t, 300, total.Total)
+
+	assert.ErrorIs(t, s.Aggregate(ctx, where.NewWhere(), &total, Sum("secret", "total")), ErrInvalidColumn)
+	assert.ErrorIs(t, s.Aggregate(ctx, where.S("nope"), &total, Sum("salary", "total")), ErrInvalidSort)
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// AuditLog is an entry of the audit trail, recording a write to a single row.
+type AuditLog struct {
+	ID uint64 `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	// Table is the table of the written row.
+	Table string `gorm:"column:table_name;size:128;not null;index:idx_audit_logs_record" json:"table"`
+	// RecordID is the primary key of the written row; fields of composite keys are joined with commas.
+	RecordID string `gorm:"column:record_id;size:255;not null;index:idx_audit_logs_record" json:"recordId"`
+	// Action is the kind of write.
+	Action Operation `gorm:"column:action;size:16;not null" json:"action"`
+	// Operator is the ID of the user who made the write, if known.
+	Operator *uint32 `gorm:"column:operator;index" json:"operator,omitempty"`
+	// Tenant holds the tenant values the write was made for.
+	Tenant map[string]string `gorm:"column:tenant;serializer:json;type:text" json:"tenant,omitempty"`
+	// Changes holds the old and new values of the written columns, keyed by column name.
+	Changes map[string]FieldChange `gorm:"column:changes;serializer:json;type:text" json:"changes"`
+	// CreatedAt is when the write was made.
+	CreatedAt time.Time `gorm:"column:created_at;not null;index" json:"createdAt"`
+}
+
+// TableName returns the table name of AuditLog.
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// FieldChange is the old and new value of a column. Old is nil for creates, New for deletes.
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Audit configures the audit trail of a Store.
+type Audit struct {
+	// OperatorFunc returns the ID of the user making the write, typically carried by ctx.
+	// It matches the operator IDs stored by the entx CreateBy/UpdateBy mixins.
+	OperatorFunc func(ctx context.Context) (uint32, bool)
+	// Ignore lists columns left out of the recorded changes, e.g. secrets or updated_at.
+	// Changes of fields encrypted by the store/encrypt serializers are recorded without
+	// their values.
+	Ignore []string
+}
+
+// WithAudit returns an Option that records every write made through the Store into the
+// audit_logs table, in the transaction of the write. Query the trail with NewAuditStore.
+func WithAudit[T any](audit Audit) Option[T] {
+	return func(s *Store[T]) {
+		s.audit = &audit
+	}
+}
+
+// NewAuditStore creates a Store over the audit trail. The audit table holds the entries of
+// every tenant and is therefore exempt from tenant isolation; filter on Tenant if needed.
+func NewAuditStore(storage DBProvider, logger Logger) *Store[AuditLog] {
+	return NewStore[AuditLog](storage, logger, WithTenantExemption[AuditLog](), WithSortableColumns[AuditLog]("id", "created_at"))
+}
+
+// recordAudit writes the audit entries of change. It must run in the transaction of the write.
+func (s *Store[T]) recordAudit(ctx context.Context, change *Change[T]) error {
+	if s.audit == nil {
+		return nil
+	}
+
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	tenant, err := s.tenantValues(ctx)
+	if err != nil {
+		return err
+	}
+	var operator *uint32
+	if s.audit.OperatorFunc != nil {
+		if id, ok := s.audit.OperatorFunc(ctx); ok {
+			operator = &id
+		}
+	}
+
+	old := make(map[string]*T, len(change.Old))
+	for _, obj := range change.Old {
+		old[primaryKeyOf(ctx, sch, obj)] = obj
+	}
+
+	now := time.Now()
+	logs := make([]*AuditLog, 0, max(len(change.Old), len(change.New)))
+	addLog := func(recordID string, before, after *T) {
+		changes := s.diff(ctx, sch, before, after)
+		if len(changes) == 0 {
+			return
+		}
+		logs = append(logs, &AuditLog{
+			Table:     sch.Table,
+			RecordID:  recordID,
+			Action:    change.Op,
+			Operator:  operator,
+			Tenant:    tenant,
+			Changes:   changes,
+			CreatedAt: now,
+		})
+	}
+
+	for _, obj := range change.New {
+		recordID := primaryKeyOf(ctx, sch, obj)
+		addLog(recordID, old[recordID], obj)
+		delete(old, recordID)
+	}
+	for _, obj := range change.Old {
+		if recordID := primaryKeyOf(ctx, sch, obj); old[recordID] != nil {
+			addLog(recordID, obj, nil)
+		}
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+
+	// The audit table is not scoped like the model, so the transaction is used directly.
+	tx, _ := txFor(ctx, s.storage)
+	return tx.WithContext(ctx).Create(&logs).Error
+}
+
+// diff returns the columns whose values differ between before and after, either of which may be nil.
+func (s *Store[T]) diff(ctx context.Context, sch *schema.Schema, before, after *T) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	for _, field := range sch.Fields {
+		if field.DBName == "" || slices.Contains(s.audit.Ignore, field.DBName) {
+			continue
+		}
+
+		var change FieldChange
+		if before != nil {
+			change.Old, _ = field.ValueOf(ctx, reflect.ValueOf(before))
+		}
+		if after != nil {
+			change.New, _ = field.ValueOf(ctx, reflect.ValueOf(after))
+		}
+		if before != nil && after != nil && sameValue(change.Old, change.New) {
+			continue
+		}
+		if isEncrypted(field) {
+			change = FieldChange{Old: redact(change.Old), New: redact(change.New)}
+		}
+		changes[field.DBName] = change
+	}
+	return changes
+}
+
+// sameValue reports whether a and b are equal, comparing times by instant since values read
+// back from the database may carry another location than the written ones.
+func sameValue(a, b any) bool {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	if at, ok := a.(*time.Time); ok {
+		bt, ok := b.(*time.Time)
+		return ok && (at == bt || at != nil && bt != nil && at.Equal(*bt))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// redactedValue replaces the values of encrypted fields in the recorded changes.
+const redactedValue = "[encrypted]"
+
+// isEncrypted reports whether field is encrypted by a serializer of the store/encrypt package.
+func isEncrypted(field *schema.Field) bool {
+	return strings.HasPrefix(field.TagSettings["SERIALIZER"], "encrypted")
+}
+
+// redact hides a value of an encrypted field, keeping track of whether it was set.
+func redact(value any) any {
+	if value == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil
+	}
+	return redactedValue
+}
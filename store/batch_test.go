@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+type testAccount struct {
+	ID    uint   `gorm:"primaryKey"`
+	Email string `gorm:"size:64;uniqueIndex"`
+	Name  string `gorm:"size:64"`
+	Age   int
+}
+
+func TestStoreBatchWrites(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &testAccount{})
+	var inserts int
+	require.NoError(t, db.Callback().Create().After("gorm:create").Register("test:count_inserts", func(*gorm.DB) {
+		inserts++
+	}))
+	s := NewStore[testAccount](NewReadWriteProvider(db, nil), nil, WithTenantExemption[testAccount]())
+
+	accounts := func() map[string]testAccount {
+		t.Helper()
+		_, list, err := s.List(ctx, where.NewWhere())
+		require.NoError(t, err)
+		ret := make(map[string]testAccount, len(list))
+		for _, a := range list {
+			ret[a.Email] = *a
+		}
+		return ret
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		require.NoError(t, s.CreateBatch(ctx, nil, 2))
+		require.NoError(t, s.Upsert(ctx, []*testAccount{}, []string{"email"}))
+		assert.Zero(t, inserts)
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		batch := []*testAccount{
+			{Email: "a@x.io", Name: "a"},
+			{Email: "b@x.io", Name: "b"},
+			{Email: "c@x.io", Name: "c"},
+			{Email: "d@x.io", Name: "d"},
+			{Email: "e@x.io", Name: "e"},
+		}
+		require.NoError(t, s.CreateBatch(ctx, batch, 2))
+		assert.Equal(t, 3, inserts)
+		for _, a := range batch {
+			assert.NotZero(t, a.ID)
+		}
+		assert.Len(t, accounts(), 5)
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		// Only the listed columns of the conflicting row are overwritten.
+		require.NoError(t, s.Upsert(ctx, []*testAccount{
+			{Email: "a@x.io", Name: "alice", Age: 30},
+			{Email: "f@x.io", Name: "f", Age: 6},
+		}, []string{"email"}, "name"))
+		got := accounts()
+		assert.Len(t, got, 6)
+		assert.Equal(t, "alice", got["a@x.io"].Name)
+		assert.Zero(t, got["a@x.io"].Age)
+		assert.Equal(t, 6, got["f@x.io"].Age)
+
+		// Without update columns, every column is overwritten.
+		require.NoError(t, s.Upsert(ctx, []*testAccount{{Email: "b@x.io", Name: "bob", Age: 20}}, []string{"email"}))
+		got = accounts()
+		assert.Len(t, got, 6)
+		assert.Equal(t, testAccount{ID: got["b@x.io"].ID, Email: "b@x.io", Name: "bob", Age: 20}, got["b@x.io"])
+	})
+
+	t.Run("update columns", func(t *testing.T) {
+		require.NoError(t, s.UpdateColumns(ctx, where.NewWhere().Q("age < ?", 10), map[string]any{"age": 99}))
+		got := accounts()
+		for email, a := range got {
+			if email == "b@x.io" {
+				assert.Equal(t, 20, a.Age)
+				continue
+			}
+			assert.Equal(t, 99, a.Age, email)
+		}
+	})
+}
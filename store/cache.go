@@ -0,0 +1,174 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/cache"
+	"github.com/moweilong/mo/store/where"
+)
+
+// queryCache holds the read-through cache configuration of a Store.
+type queryCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+// WithCache returns an Option that caches the results of Get and List in c for ttl.
+// Results are keyed by the model and the SQL of the query, which includes the where
+// options and the tenant scope. Under a TenantProvider, they are also keyed by the
+// database it chose. They are invalidated by every write made through a Store of the
+// same model, once its transaction commits. Concurrent misses of the same query share a
+// single database round trip. Queries inside a transaction or marked with WithPrimary
+// bypass the cache. T must be encodable with encoding/gob.
+//
+// Use cache.NewFallback(cache.NewRedisCache(...), cache.NewLRU(...)) to keep caching
+// in process while Redis is unavailable.
+func WithCache[T any](c cache.Cache, ttl time.Duration) Option[T] {
+	return func(s *Store[T]) {
+		s.cache = &queryCache{cache: c, ttl: ttl}
+	}
+}
+
+// listResult is the cached result of List.
+type listResult[T any] struct {
+	Count int64
+	Items []*T
+}
+
+// readThrough runs load, which fills dest, through the cache. stmt returns a dry run of the
+// query, from which the cache key is derived. Queries loading associations are not cached,
+// since writes to the associated models do not invalidate them. Cache failures are logged
+// and fall back to load.
+func (s *Store[T]) readThrough(
+	ctx context.Context,
+	kind string,
+	opts *where.Options,
+	stmt func() *gorm.DB,
+	dest any,
+	load func() error,
+) error {
+	if s.cache == nil || PrimaryForced(ctx) || len(opts.Preloads) > 0 || len(opts.Joined) > 0 {
+		return load()
+	}
+	if _, inTx := txFor(ctx, s.storage); inTx {
+		return load()
+	}
+
+	dry := stmt()
+	if dry.Error != nil {
+		return load()
+	}
+	key, err := s.cacheKey(ctx, kind, dry)
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to build query cache key")
+		return load()
+	}
+
+	value, err := s.cache.cache.Get(ctx, key)
+	if err == nil {
+		if err = gob.NewDecoder(bytes.NewReader(value)).Decode(dest); err == nil {
+			return nil
+		}
+	}
+	if !errors.Is(err, cache.ErrMiss) {
+		s.logger.Error(ctx, err, "Failed to read query cache", "key", key)
+	}
+
+	loaded := false
+	shared, err, _ := s.cache.group.Do(key, func() (any, error) {
+		loaded = true
+		if err := load(); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(dest); err != nil {
+			s.logger.Error(ctx, err, "Failed to encode query result for cache", "key", key)
+			return nil, nil
+		}
+		if err := s.cache.cache.Set(context.WithoutCancel(ctx), key, buf.Bytes(), s.cache.ttl); err != nil {
+			s.logger.Error(ctx, err, "Failed to write query cache", "key", key)
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil || loaded {
+		return err
+	}
+	if shared == nil {
+		// The shared result could not be encoded, so it is loaded again.
+		return load()
+	}
+	return gob.NewDecoder(bytes.NewReader(shared.([]byte))).Decode(dest)
+}
+
+// cacheKey returns the cache key of the query dry-run in stmt, scoped to the current
+// generation of the model's cache.
+func (s *Store[T]) cacheKey(ctx context.Context, kind string, stmt *gorm.DB) (string, error) {
+	// Sharded queries read a shard table, but are invalidated with their model.
+	table := stmt.Statement.Table
+	if stmt.Statement.Schema != nil {
+		table = stmt.Statement.Schema.Table
+	}
+	var generation int64
+	value, err := s.cache.cache.Get(ctx, cacheGenerationKey(table))
+	if err == nil {
+		_, err = fmt.Sscan(string(value), &generation)
+	}
+	if err != nil && !errors.Is(err, cache.ErrMiss) {
+		return "", err
+	}
+
+	query := stmt.Dialector.Explain(stmt.Statement.SQL.String(), stmt.Statement.Vars...)
+	// The same query returns different rows on the databases of different tenants.
+	if database, ok := stmt.Get(databaseSetting); ok {
+		query = database.(string) + ":" + query
+	}
+	sum := sha256.Sum256([]byte(query))
+	return fmt.Sprintf("store:%s:%d:%s:%s", table, generation, kind, hex.EncodeToString(sum[:])), nil
+}
+
+// invalidateCache drops the cached results of the model by moving to a new cache generation,
+// both now and once the transaction carried by ctx commits, so that results cached from
+// uncommitted state in between are discarded too.
+func (s *Store[T]) invalidateCache(ctx context.Context) {
+	if s.cache == nil {
+		return
+	}
+	sch, err := s.schema(ctx)
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to invalidate query cache")
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	bump := func() {
+		if _, err := s.cache.cache.Incr(ctx, cacheGenerationKey(sch.Table)); err != nil {
+			s.logger.Error(ctx, err, "Failed to invalidate query cache", "table", sch.Table)
+		}
+	}
+	bump()
+	if _, inTx := txFor(ctx, s.storage); inTx {
+		afterCommit(ctx, s.storage, bump)
+	}
+}
+
+// cacheGenerationKey returns the key of the cache generation of table.
+func cacheGenerationKey(table string) string {
+	return "store:" + table + ":generation"
+}
+
+// dryRun returns a session of db that builds statements without executing them.
+func dryRun(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{DryRun: true})
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moweilong/mo/cache"
+	"github.com/moweilong/mo/store/where"
+)
+
+func TestStoreCache(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &testUser{})
+	lru := cache.NewLRU(100)
+	provider := NewReadWriteProvider(db, nil)
+	s := NewStore[testUser](provider, nil, WithTenantExemption[testUser](), WithCache[testUser](lru, time.Minute))
+	require.NoError(t, s.Create(ctx, &testUser{Name: "alice", Age: 30}))
+
+	generation := func() int64 {
+		t.Helper()
+		value, err := lru.Get(ctx, cacheGenerationKey("test_users"))
+		require.NoError(t, err)
+		n, err := strconv.ParseInt(string(value), 10, 64)
+		require.NoError(t, err)
+		return n
+	}
+	count := func() int64 {
+		t.Helper()
+		n, _, err := s.List(ctx, where.NewWhere())
+		require.NoError(t, err)
+		return n
+	}
+	age := func() int {
+		t.Helper()
+		user, err := s.Get(ctx, where.F("name", "alice"))
+		require.NoError(t, err)
+		return user.Age
+	}
+
+	// Rows written behind the Store's back are not seen until a write invalidates the cache.
+	assert.Equal(t, int64(1), count())
+	assert.Equal(t, 30, age())
+	require.NoError(t, db.Create(&testUser{Name: "bob"}).Error)
+	require.NoError(t, db.Model(&testUser{}).Where("name = ?", "alice").Update("age", 31).Error)
+	assert.Equal(t, int64(1), count())
+	assert.Equal(t, 30, age())
+
+	t.Run("create", func(t *testing.T) {
+		before := generation()
+		require.NoError(t, s.Create(ctx, &testUser{Name: "carol"}))
+		assert.Equal(t, before+1, generation())
+		assert.Equal(t, int64(3), count())
+		assert.Equal(t, 31, age())
+	})
+
+	t.Run("update", func(t *testing.T) {
+		user, err := s.Get(ctx, where.F("name", "alice"))
+		require.NoError(t, err)
+		before := generation()
+		user.Age = 32
+		require.NoError(t, s.Update(ctx, user))
+		assert.Equal(t, before+1, generation())
+		assert.Equal(t, 32, age())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		before := generation()
+		require.NoError(t, s.Delete(ctx, where.F("name", "bob")))
+		assert.Equal(t, before+1, generation())
+		assert.Equal(t, int64(2), count())
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		m := NewTxManager(provider, nil)
+
+		// The cache is invalidated by the write and again once the transaction commits, so that
+		// results cached in between, from the uncommitted state, are dropped too.
+		before := generation()
+		require.NoError(t, m.Tx(ctx, func(ctx context.Context) error {
+			require.NoError(t, s.Create(ctx, &testUser{Name: "dave"}))
+			assert.Equal(t, before+1, generation())
+			return nil
+		}))
+		assert.Equal(t, before+2, generation())
+		assert.Equal(t, int64(3), count())
+
+		// A rolled back transaction does not invalidate the cache on commit.
+		errRollback := errors.New("rollback")
+		before = generation()
+		err := m.Tx(ctx, func(ctx context.Context) error {
+			require.NoError(t, s.Create(ctx, &testUser{Name: "erin"}))
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+		assert.Equal(t, before+1, generation())
+		assert.Equal(t, int64(3), count())
+	})
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// DataScopeColumns names the columns of a model that data scopes restrict rows by.
+// A column left empty denies access to callers whose scope level relies on it.
+type DataScopeColumns struct {
+	// Owner is the column holding the ID of the user owning a row, e.g. created_by.
+	Owner string
+	// Dept is the column holding the ID of the department a row belongs to, e.g. dept_id.
+	Dept string
+}
+
+// WithDataScope returns an Option that restricts the queries, updates and deletes of the
+// Store to the rows within the data scope carried by the context (see where.WithDataScope).
+// Operations fail with where.ErrMissingDataScope when the context carries no scope.
+// Creates are not restricted.
+func WithDataScope[T any](columns DataScopeColumns) Option[T] {
+	return func(s *Store[T]) {
+		s.dataScope = &columns
+	}
+}
+
+// dataScopeCondition returns the condition restricting the model to the data scope carried
+// by ctx, or nil when the Store has no data scope or the scope grants access to every row.
+func (s *Store[T]) dataScopeCondition(ctx context.Context) (clause.Expression, error) {
+	if s.dataScope == nil {
+		return nil, nil
+	}
+	scope, ok := where.DataScopeFromContext(ctx)
+	if !ok {
+		return nil, where.ErrMissingDataScope
+	}
+
+	switch scope.Level {
+	case where.DataScopeAll:
+		return nil, nil
+	case where.DataScopeSelf:
+		return scopeValues(scope.Level, s.dataScope.Owner, "user", scope.UserID)
+	case where.DataScopeDept:
+		return scopeValues(scope.Level, s.dataScope.Dept, "department", scope.DeptID)
+	case where.DataScopeDeptAndChildren:
+		return scopeValues(scope.Level, s.dataScope.Dept, "department", scope.DeptID, scope.ChildDeptIDs...)
+	default:
+		return nil, fmt.Errorf("unknown data scope level %q", scope.Level)
+	}
+}
+
+// scopeValues builds the condition matching column against the ID of the caller's user or
+// department and, for departments, the IDs of its descendants.
+func scopeValues(level where.DataScopeLevel, column, kind string, id any, more ...any) (clause.Expression, error) {
+	if column == "" {
+		return nil, fmt.Errorf("data scope %q requires a %s column on the model", level, kind)
+	}
+	if id == nil {
+		return nil, fmt.Errorf("%w: %s ID", where.ErrMissingDataScope, kind)
+	}
+
+	col := clause.Column{Table: clause.CurrentTable, Name: column}
+	if len(more) == 0 {
+		return clause.Eq{Column: col, Value: id}, nil
+	}
+	return clause.IN{Column: col, Values: append([]any{id}, more...)}, nil
+}
+
+// dataScopeFilter restricts db to the rows within the data scope carried by ctx. Like
+// tenantScope, errors are added to db so that the statement fails instead of leaking rows.
+func (s *Store[T]) dataScopeFilter(ctx context.Context, db *gorm.DB) *gorm.DB {
+	cond, err := s.dataScopeCondition(ctx)
+	if err != nil {
+		db = db.Session(&gorm.Session{})
+		_ = db.AddError(err)
+		return db
+	}
+	if cond != nil {
+		db = db.Where(cond)
+	}
+	return db
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+type testTicket struct {
+	ID        uint   `gorm:"primaryKey"`
+	CreatedBy uint32 `gorm:"index"`
+	DeptID    uint32 `gorm:"index"`
+	Title     string `gorm:"size:64"`
+}
+
+func TestStoreDataScope(t *testing.T) {
+	db := newTestDB(t, &testTicket{})
+	s := NewStore[testTicket](NewReadWriteProvider(db, nil), nil,
+		WithTenantExemption[testTicket](),
+		WithDataScope[testTicket](DataScopeColumns{Owner: "created_by", Dept: "dept_id"}),
+	)
+
+	all := where.WithDataScope(context.Background(), where.DataScope{Level: where.DataScopeAll})
+	// Department 1 has the child departments 2 and 3; users 1 to 4 belong to departments 1 to 4.
+	for i := uint32(1); i <= 4; i++ {
+		require.NoError(t, s.Create(all, &testTicket{CreatedBy: i, DeptID: i, Title: "t"}))
+	}
+
+	owners := func(scope where.DataScope) []uint32 {
+		t.Helper()
+		_, tickets, err := s.List(where.WithDataScope(context.Background(), scope), where.S("id"))
+		require.NoError(t, err)
+		ret := make([]uint32, 0, len(tickets))
+		for _, ticket := range tickets {
+			ret = append(ret, ticket.CreatedBy)
+		}
+		return ret
+	}
+
+	t.Run("levels", func(t *testing.T) {
+		assert.Equal(t, []uint32{1, 2, 3, 4}, owners(where.DataScope{Level: where.DataScopeAll}))
+		assert.Equal(t, []uint32{2}, owners(where.DataScope{Level: where.DataScopeSelf, UserID: uint32(2), DeptID: uint32(1)}))
+		assert.Equal(t, []uint32{1}, owners(where.DataScope{Level: where.DataScopeDept, UserID: uint32(2), DeptID: uint32(1)}))
+		assert.Equal(t, []uint32{1, 2, 3}, owners(where.DataScope{
+			Level: where.DataScopeDeptAndChildren, DeptID: uint32(1), ChildDeptIDs: []any{uint32(2), uint32(3)},
+		}))
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		_, _, err := s.List(context.Background(), where.NewWhere())
+		assert.ErrorIs(t, err, where.ErrMissingDataScope)
+
+		_, _, err = s.List(where.WithDataScope(context.Background(), where.DataScope{Level: where.DataScopeSelf}), where.NewWhere())
+		assert.ErrorIs(t, err, where.ErrMissingDataScope)
+
+		_, _, err = s.List(where.WithDataScope(context.Background(), where.DataScope{Level: "team"}), where.NewWhere())
+		assert.Error(t, err)
+	})
+
+	t.Run("writes are filtered", func(t *testing.T) {
+		self := where.WithDataScope(context.Background(), where.DataScope{Level: where.DataScopeSelf, UserID: uint32(1)})
+
+		require.NoError(t, s.UpdateColumns(self, where.F("id", 2), map[string]any{"title": "hacked"}))
+		require.NoError(t, s.Update(self, &testTicket{ID: 2, CreatedBy: 1, DeptID: 2, Title: "hacked"}))
+		require.NoError(t, s.Delete(self, where.F("id", 2)))
+		count, err := s.Count(self, where.NewWhere())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		ticket, err := s.Get(all, where.F("id", 2))
+		require.NoError(t, err)
+		assert.Equal(t, "t", ticket.Title)
+		assert.Equal(t, uint32(2), ticket.CreatedBy)
+
+		require.NoError(t, s.UpdateColumns(self, where.F("id", 1), map[string]any{"title": "mine"}))
+		ticket, err = s.Get(self, where.F("id", 1))
+		require.NoError(t, err)
+		assert.Equal(t, "mine", ticket.Title)
+	})
+}
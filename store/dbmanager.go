@@ -0,0 +1,150 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownDB is returned when a database is not registered with a DBManager.
+var ErrUnknownDB = errors.New("unknown database")
+
+// DBOpener opens a database. *options.MySQLOptions, *options.PostgreSQLOptions and
+// *options.SQLiteOptions implement it.
+type DBOpener interface {
+	NewDB() (*gorm.DB, error)
+}
+
+// DBOpenerFunc adapts a function to a DBOpener.
+type DBOpenerFunc func() (*gorm.DB, error)
+
+// NewDB calls f.
+func (f DBOpenerFunc) NewDB() (*gorm.DB, error) {
+	return f()
+}
+
+// managedDB is a database of a DBManager, opened on first use.
+type managedDB struct {
+	opener DBOpener
+
+	mu     sync.Mutex
+	db     *gorm.DB
+	closed bool
+}
+
+// get returns the database, opening it unless it is already open.
+func (m *managedDB) get(name string) (*gorm.DB, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDB, name)
+	}
+	if m.db == nil {
+		db, err := m.opener.NewDB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database %s: %w", name, err)
+		}
+		m.db = db
+	}
+	return m.db, nil
+}
+
+// close closes the database if it was opened.
+func (m *managedDB) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	if m.db == nil {
+		return nil
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// DBManager holds named databases. Each database is opened on first use and its
+// connection pool is then shared by all its users. Databases can be added and removed
+// while the manager is in use.
+type DBManager struct {
+	mu  sync.RWMutex
+	dbs map[string]*managedDB
+}
+
+// NewDBManager creates a DBManager holding the databases opened by openers, by name.
+// No database is opened until it is used.
+func NewDBManager(openers map[string]DBOpener) *DBManager {
+	m := &DBManager{dbs: make(map[string]*managedDB, len(openers))}
+	for name, opener := range openers {
+		m.dbs[name] = &managedDB{opener: opener}
+	}
+	return m
+}
+
+// Get returns the database registered under name, opening it on first use. A database
+// that fails to open is opened again by the next call.
+func (m *DBManager) Get(name string) (*gorm.DB, error) {
+	m.mu.RLock()
+	db, ok := m.dbs[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDB, name)
+	}
+	return db.get(name)
+}
+
+// Add registers the database opened by opener under name. A database already registered
+// under name is replaced and closed.
+func (m *DBManager) Add(name string, opener DBOpener) error {
+	m.mu.Lock()
+	old := m.dbs[name]
+	m.dbs[name] = &managedDB{opener: opener}
+	m.mu.Unlock()
+
+	if old != nil {
+		return old.close()
+	}
+	return nil
+}
+
+// Remove unregisters the database registered under name and closes it. Statements still
+// running on it fail.
+func (m *DBManager) Remove(name string) error {
+	m.mu.Lock()
+	db, ok := m.dbs[name]
+	delete(m.dbs, name)
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDB, name)
+	}
+	return db.close()
+}
+
+// Names returns the names of the registered databases, sorted.
+func (m *DBManager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Sorted(maps.Keys(m.dbs))
+}
+
+// Close closes every opened database and unregisters all of them.
+func (m *DBManager) Close() error {
+	m.mu.Lock()
+	dbs := m.dbs
+	m.dbs = map[string]*managedDB{}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, db := range dbs {
+		errs = append(errs, db.close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/gormx"
+	"github.com/moweilong/mo/store"
+	"github.com/moweilong/mo/store/where"
+)
+
+type customer struct {
+	ID     uint    `gorm:"primaryKey"`
+	Phone  string  `gorm:"size:255;serializer:encrypted"`
+	IDCard *string `gorm:"size:255;serializer:encrypted_deterministic"`
+}
+
+func newKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+	k, err := NewKeyring(primary, keys)
+	require.NoError(t, err)
+	return k
+}
+
+func TestKeyring(t *testing.T) {
+	k := newKeyring(t, "k1", "k1")
+
+	c1, err := k.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	c2, err := k.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	assert.NotEqual(t, c1, c2)
+	assert.Equal(t, k.EncryptDeterministic([]byte("secret")), k.EncryptDeterministic([]byte("secret")))
+	assert.NotEqual(t, k.EncryptDeterministic([]byte("secret")), k.EncryptDeterministic([]byte("Secret")))
+
+	plaintext, err := k.Decrypt(c1)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	_, err = k.Decrypt("k1:" + c1[len("k1:"):len(c1)-2])
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = k.Decrypt("k2" + c1[len("k1"):])
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = NewKeyring("k2", map[string][]byte{"k1": make([]byte, 32)})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = NewKeyring("k1", map[string][]byte{"k1": make([]byte, 10)})
+	assert.Error(t, err)
+}
+
+func TestSerializer(t *testing.T) {
+	ctx := context.Background()
+	db, err := gormx.NewSQLite(&gormx.SQLiteOptions{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&customer{}, &store.AuditLog{}))
+	s := store.NewStore[customer](store.NewReadWriteProvider(db, nil), nil, store.WithAudit[customer](store.Audit{}))
+
+	RegisterKeyring(newKeyring(t, "k1", "k1"))
+	t.Cleanup(func() { RegisterKeyring(nil) })
+
+	idCard := "110101199003071234"
+	require.NoError(t, s.Create(ctx, &customer{Phone: "13800000000", IDCard: &idCard}))
+	require.NoError(t, s.Create(ctx, &customer{Phone: "13900000000"}))
+
+	var raw struct {
+		Phone  string
+		IDCard *string
+	}
+	require.NoError(t, db.Table("customers").Where("id = ?", 1).Scan(&raw).Error)
+	assert.NotContains(t, raw.Phone, "13800000000")
+	require.NotNil(t, raw.IDCard)
+	assert.NotContains(t, *raw.IDCard, idCard)
+
+	var log store.AuditLog
+	require.NoError(t, db.First(&log).Error)
+	assert.Equal(t, store.FieldChange{New: "[encrypted]"}, log.Changes["phone"])
+
+	got, err := s.Get(ctx, where.F("id", 1))
+	require.NoError(t, err)
+	assert.Equal(t, "13800000000", got.Phone)
+	assert.Equal(t, &idCard, got.IDCard)
+
+	got, err = s.Get(ctx, where.F("id", 2))
+	require.NoError(t, err)
+	assert.Nil(t, got.IDCard)
+
+	// After a rotation, old rows are still readable and found by the deterministic column.
+	RegisterKeyring(newKeyring(t, "k2", "k1", "k2"))
+	other := "110101199003075678"
+	require.NoError(t, s.Create(ctx, &customer{Phone: "13700000000", IDCard: &other}))
+
+	for _, card := range []string{idCard, other} {
+		got, err = s.Get(ctx, where.F("id_card", Deterministic(card)))
+		require.NoError(t, err)
+		assert.Equal(t, card, *got.IDCard)
+	}
+	_, err = s.Get(ctx, where.F("id_card", Deterministic("unknown")))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	RegisterKeyring(newKeyring(t, "k2", "k2"))
+	_, err = s.Get(ctx, where.F("id", 1))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	RegisterKeyring(nil)
+	assert.ErrorIs(t, s.Create(ctx, &customer{Phone: "13600000000"}), ErrNoKeyring)
+}
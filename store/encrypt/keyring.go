@@ -0,0 +1,181 @@
+// Package encrypt provides transparent field-level encryption of GORM models with AES-GCM.
+//
+// Fields tagged `gorm:"serializer:encrypted"` are encrypted with a random nonce, so equal
+// values give different ciphertexts. Fields tagged `gorm:"serializer:encrypted_deterministic"`
+// derive the nonce from the value (a blind index), so equal values give equal ciphertexts
+// under a given key and the column can be used in equality filters through Deterministic.
+//
+// Ciphertexts are stored as "<key ID>:<base64>" strings. New values are encrypted with the
+// primary key of the registered Keyring, and values encrypted with any of its keys can be
+// decrypted, which allows rotating keys without re-encrypting existing rows at once.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNoKeyring is returned when encrypted fields are used before a keyring is registered.
+	ErrNoKeyring = errors.New("no encryption keyring registered")
+	// ErrUnknownKey is returned when a ciphertext references a key missing from the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrInvalidCiphertext is returned when a ciphertext is malformed or fails authentication.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// keySeparator separates the key ID from the encoded ciphertext.
+const keySeparator = ":"
+
+// nonceLabel derives the key of the deterministic nonces from an encryption key.
+const nonceLabel = "mo/store/encrypt deterministic nonce"
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *Keyring
+)
+
+// RegisterKeyring registers the keyring used by the encrypted serializers.
+func RegisterKeyring(k *Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	keyring = k
+}
+
+// registered returns the registered keyring or ErrNoKeyring.
+func registered() (*Keyring, error) {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	if keyring == nil {
+		return nil, ErrNoKeyring
+	}
+	return keyring, nil
+}
+
+// key is an encryption key of a Keyring.
+type key struct {
+	id    string
+	aead  cipher.AEAD
+	nonce []byte // HMAC key of the deterministic nonces
+}
+
+// Keyring holds the AES keys used to encrypt and decrypt fields, identified by key IDs.
+type Keyring struct {
+	primary *key
+	keys    map[string]*key
+}
+
+// NewKeyring creates a keyring from AES keys of 16, 24 or 32 bytes indexed by key ID.
+// New values are encrypted with the key identified by primary.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*key, len(keys))}
+	for id, secret := range keys {
+		if id == "" || strings.Contains(id, keySeparator) {
+			return nil, fmt.Errorf("invalid encryption key ID %q", id)
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(nonceLabel))
+		k.keys[id] = &key{id: id, aead: aead, nonce: mac.Sum(nil)}
+	}
+
+	var ok bool
+	if k.primary, ok = k.keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: primary key %q", ErrUnknownKey, primary)
+	}
+	return k, nil
+}
+
+// Encrypt encrypts plaintext with the primary key and a random nonce.
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, k.primary.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return k.primary.seal(nonce, plaintext), nil
+}
+
+// EncryptDeterministic encrypts plaintext with the primary key and a nonce derived from it,
+// so that equal plaintexts give equal ciphertexts.
+func (k *Keyring) EncryptDeterministic(plaintext []byte) string {
+	return k.primary.sealDeterministic(plaintext)
+}
+
+// Deterministic returns the deterministic ciphertexts of plaintext under every key of the
+// keyring, sorted, so that rows written before a key rotation still match.
+func (k *Keyring) Deterministic(plaintext []byte) []string {
+	ret := make([]string, 0, len(k.keys))
+	for _, key := range k.keys {
+		ret = append(ret, key.sealDeterministic(plaintext))
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// Decrypt decrypts a ciphertext produced by any key of the keyring.
+func (k *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	id, encoded, ok := strings.Cut(ciphertext, keySeparator)
+	if !ok {
+		return nil, ErrInvalidCiphertext
+	}
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < key.aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, sealed := data[:key.aead.NonceSize()], data[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+// seal encrypts plaintext with nonce, authenticating the key ID along with it.
+func (k *key) seal(nonce, plaintext []byte) string {
+	data := k.aead.Seal(nonce, nonce, plaintext, []byte(k.id))
+	return k.id + keySeparator + base64.RawStdEncoding.EncodeToString(data)
+}
+
+// sealDeterministic encrypts plaintext with a nonce derived from it.
+func (k *key) sealDeterministic(plaintext []byte) string {
+	mac := hmac.New(sha256.New, k.nonce)
+	mac.Write(plaintext)
+	return k.seal(mac.Sum(nil)[:k.aead.NonceSize()], plaintext)
+}
+
+// Deterministic returns the values matching plaintext in a column of a field tagged
+// `gorm:"serializer:encrypted_deterministic"`, for use as an equality filter:
+//
+//	where.F("id_card", encrypt.Deterministic(idCard))
+//
+// It holds one ciphertext per key of the registered keyring, so the filter is an IN condition
+// that also matches rows written with a previous primary key. Without a registered keyring,
+// it is empty and matches nothing.
+func Deterministic(plaintext string) []string {
+	k, err := registered()
+	if err != nil {
+		return []string{}
+	}
+	return k.Deterministic([]byte(plaintext))
+}
@@ -0,0 +1,106 @@
+package encrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// SerializerName is the name of the randomized serializer, `gorm:"serializer:encrypted"`.
+	SerializerName = "encrypted"
+	// DeterministicSerializerName is the name of the deterministic serializer,
+	// `gorm:"serializer:encrypted_deterministic"`.
+	DeterministicSerializerName = "encrypted_deterministic"
+)
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+	schema.RegisterSerializer(DeterministicSerializerName, Serializer{Deterministic: true})
+}
+
+// Serializer encrypts string and []byte fields, or pointers to them, with the registered
+// keyring. Nil pointers are stored as NULL.
+type Serializer struct {
+	// Deterministic derives the nonce from the value instead of drawing it at random.
+	Deterministic bool
+}
+
+var _ schema.SerializerInterface = Serializer{}
+
+// Scan implements schema.SerializerInterface by decrypting dbValue into the field.
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.New(field.FieldType).Elem()
+	if dbValue != nil {
+		var ciphertext string
+		switch v := dbValue.(type) {
+		case string:
+			ciphertext = v
+		case []byte:
+			ciphertext = string(v)
+		default:
+			return fmt.Errorf("%w: unsupported column value %T of field %s", ErrInvalidCiphertext, dbValue, field.Name)
+		}
+
+		k, err := registered()
+		if err != nil {
+			return err
+		}
+		plaintext, err := k.Decrypt(ciphertext)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		target := fieldValue
+		if target.Kind() == reflect.Pointer {
+			target.Set(reflect.New(target.Type().Elem()))
+			target = target.Elem()
+		}
+		switch {
+		case target.Kind() == reflect.String:
+			target.SetString(string(plaintext))
+		case target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.Uint8:
+			target.SetBytes(plaintext)
+		default:
+			return fmt.Errorf("encrypted field %s must be a string or []byte, got %s", field.Name, field.FieldType)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue)
+	return nil
+}
+
+// Value implements schema.SerializerInterface by encrypting fieldValue.
+func (s Serializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, fieldValue any) (any, error) {
+	if fieldValue == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(fieldValue)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	var plaintext []byte
+	switch {
+	case rv.Kind() == reflect.String:
+		plaintext = []byte(rv.String())
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		plaintext = rv.Bytes()
+	default:
+		return nil, fmt.Errorf("encrypted field %s must be a string or []byte, got %s", field.Name, field.FieldType)
+	}
+
+	k, err := registered()
+	if err != nil {
+		return nil, err
+	}
+	if s.Deterministic {
+		return k.EncryptDeterministic(plaintext), nil
+	}
+	return k.Encrypt(plaintext)
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrInvalidFilter is returned when a filter of where.FromFilterJSON references an unknown or
+// non-filterable column.
+var ErrInvalidFilter = errors.New("invalid filter column")
+
+// WithFilterableColumns returns an Option that restricts the columns the filters built by
+// where.FromFilterJSON may reference. Columns can be given as column or field names.
+// Without it, any column of the model is filterable, so stores exposing filters to clients
+// should list their columns to keep secret ones from being probed, e.g. with LIKE filters.
+func WithFilterableColumns[T any](columns ...string) Option[T] {
+	return func(s *Store[T]) {
+		s.filterable = make(map[string]struct{}, len(columns))
+		for _, col := range columns {
+			s.filterable[col] = struct{}{}
+		}
+	}
+}
+
+// checkFilters verifies that the filters of wheres only reference real, filterable columns
+// of the model.
+func (s *Store[T]) checkFilters(db *gorm.DB, wheres []where.Where) error {
+	var columns []string
+	for _, whr := range wheres {
+		if opts, ok := whr.(*where.Options); ok && opts != nil {
+			columns = append(columns, opts.FilterColumns()...)
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return err
+	}
+	for _, col := range columns {
+		field := stmt.Schema.LookUpField(col)
+		if field == nil || field.DBName == "" || !s.isFilterable(col, field.DBName, field.Name) {
+			return fmt.Errorf("%w: %s", ErrInvalidFilter, col)
+		}
+	}
+	return nil
+}
+
+// isFilterable reports whether the column referenced by any of keys may be filtered on.
+func (s *Store[T]) isFilterable(keys ...string) bool {
+	if s.filterable == nil {
+		return true
+	}
+	for _, key := range keys {
+		if _, ok := s.filterable[key]; ok {
+			return true
+		}
+	}
+	return false
+}
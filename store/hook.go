@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// Operation is the kind of write observed by hooks and the audit trail.
+type Operation string
+
+const (
+	// OpCreate covers Create, CreateBatch and Upsert.
+	OpCreate Operation = "create"
+	// OpUpdate covers Update, UpdateColumns and Restore.
+	OpUpdate Operation = "update"
+	// OpDelete covers Delete and HardDelete.
+	OpDelete Operation = "delete"
+)
+
+// Change describes a write made through a Store.
+type Change[T any] struct {
+	// Op is the kind of write.
+	Op Operation
+	// Old holds the affected objects as they were before the write. It is empty for creates.
+	Old []*T
+	// New holds the objects as written. Before hooks of Create and Update may modify them.
+	// For UpdateColumns and Restore, it is only filled for after hooks. It is empty for deletes.
+	New []*T
+	// Conditions holds the where options of condition-based writes.
+	Conditions *where.Options
+}
+
+// HookFunc observes a Change. An error returned by a hook aborts the write, or rolls it back
+// when returned by an after hook.
+type HookFunc[T any] func(ctx context.Context, change *Change[T]) error
+
+// hookKey identifies the hooks of a model type, operation and stage.
+type hookKey struct {
+	typ   reflect.Type
+	op    Operation
+	after bool
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   = map[hookKey][]any{}
+)
+
+// RegisterBeforeHook registers fn to run before every op write of a T, in every Store of T.
+// Hooks run in registration order, in the transaction of the write.
+func RegisterBeforeHook[T any](op Operation, fn HookFunc[T]) {
+	registerHook(hookKey{typ: reflect.TypeFor[T](), op: op}, fn)
+}
+
+// RegisterAfterHook registers fn to run after every successful op write of a T, in every Store of T.
+// Hooks run in registration order, in the transaction of the write.
+func RegisterAfterHook[T any](op Operation, fn HookFunc[T]) {
+	registerHook(hookKey{typ: reflect.TypeFor[T](), op: op, after: true}, fn)
+}
+
+// registerHook appends fn to the hooks of key.
+func registerHook(key hookKey, fn any) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks[key] = append(hooks[key], fn)
+}
+
+// hooksOf returns the hooks of T for op at the given stage.
+func hooksOf[T any](op Operation, after bool) []HookFunc[T] {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+
+	registered := hooks[hookKey{typ: reflect.TypeFor[T](), op: op, after: after}]
+	fns := make([]HookFunc[T], 0, len(registered))
+	for _, fn := range registered {
+		fns = append(fns, fn.(HookFunc[T]))
+	}
+	return fns
+}
+
+// runHooks runs fns in order, stopping at the first error.
+func runHooks[T any](ctx context.Context, fns []HookFunc[T], change *Change[T]) error {
+	for _, fn := range fns {
+		if err := fn(ctx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observe runs write together with the hooks and the audit trail of change.Op, in a single
+// transaction, and invalidates the query cache once it succeeds. load, when set, reads the
+// affected rows before the write into change.Old; reload makes the rows of change.Old be read
+// again after the write into change.New. Without hooks or audit, write runs alone and nothing is read.
+func (s *Store[T]) observe(
+	ctx context.Context,
+	change *Change[T],
+	load func(ctx context.Context) ([]*T, error),
+	reload bool,
+	write func(ctx context.Context) error,
+) error {
+	if err := s.observed(ctx, change, load, reload, write); err != nil {
+		return err
+	}
+	s.invalidateCache(ctx)
+	return nil
+}
+
+// observed implements observe, except for the cache invalidation.
+func (s *Store[T]) observed(
+	ctx context.Context,
+	change *Change[T],
+	load func(ctx context.Context) ([]*T, error),
+	reload bool,
+	write func(ctx context.Context) error,
+) error {
+	before, after := hooksOf[T](change.Op, false), hooksOf[T](change.Op, true)
+	if len(before) == 0 && len(after) == 0 && s.audit == nil {
+		return write(ctx)
+	}
+
+	return runTx(ctx, s.storage, func(ctx context.Context) error {
+		if load != nil {
+			old, err := load(ctx)
+			if err != nil {
+				return err
+			}
+			change.Old = old
+		}
+		if err := runHooks(ctx, before, change); err != nil {
+			return err
+		}
+		if err := write(ctx); err != nil {
+			return err
+		}
+		if reload && len(change.Old) > 0 {
+			objs, err := s.findByPrimaryKeys(ctx, change.Old)
+			if err != nil {
+				return err
+			}
+			change.New = objs
+		}
+		if err := runHooks(ctx, after, change); err != nil {
+			return err
+		}
+		return s.recordAudit(ctx, change)
+	})
+}
+
+// find returns all objects matching opts, regardless of its pagination, like the
+// condition-based writes do.
+func (s *Store[T]) find(ctx context.Context, opts *where.Options) ([]*T, error) {
+	var objs []*T
+	return objs, s.db(ctx, opts).Offset(-1).Limit(-1).Find(&objs).Error
+}
+
+// findByPrimaryKeys reads objs again from the database, soft-deleted or not.
+func (s *Store[T]) findByPrimaryKeys(ctx context.Context, objs []*T) ([]*T, error) {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := primaryKeyCondition(ctx, sch, objs)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*T
+	return found, s.db(ctx, where.NewWhere(where.WithDeleted())).Where(cond).Find(&found).Error
+}
+
+// primaryKeyCondition builds the condition matching objs by primary key.
+func primaryKeyCondition[T any](ctx context.Context, sch *schema.Schema, objs []*T) (clause.Expression, error) {
+	if len(sch.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("model %s has no primary key", sch.Name)
+	}
+
+	ors := make([]clause.Expression, 0, len(objs))
+	for _, obj := range objs {
+		ands := make([]clause.Expression, 0, len(sch.PrimaryFields))
+		for _, field := range sch.PrimaryFields {
+			value, _ := field.ValueOf(ctx, reflect.ValueOf(obj))
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...), nil
+}
+
+// primaryKeyOf formats the primary key of obj, joining the fields of composite keys with commas.
+func primaryKeyOf(ctx context.Context, sch *schema.Schema, obj any) string {
+	keys := make([]string, 0, len(sch.PrimaryFields))
+	for _, field := range sch.PrimaryFields {
+		value, _ := field.ValueOf(ctx, reflect.ValueOf(obj))
+		keys = append(keys, fmt.Sprint(value))
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// testTask is only used by the hook tests, since hooks are registered per model type for
+// the whole process.
+type testTask struct {
+	ID    uint   `gorm:"primaryKey"`
+	Title string `gorm:"size:64"`
+	Done  bool
+}
+
+type testProfile struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:64"`
+	Age  int
+}
+
+// errHookRejected is returned by the hooks of testTask.
+var errHookRejected = errors.New("rejected")
+
+// hookRecord is what the hooks of testTask observed, carried by the context of the writes.
+type hookRecord struct {
+	afterInTx bool
+	deleted   []string
+}
+
+type hookRecordKey struct{}
+
+// registerTaskHooks registers the hooks of testTask once, since hooks cannot be unregistered.
+var registerTaskHooks = sync.OnceFunc(func() {
+	RegisterBeforeHook(OpCreate, func(ctx context.Context, change *Change[testTask]) error {
+		for _, task := range change.New {
+			if task.Title == "" {
+				return errHookRejected
+			}
+		}
+		return nil
+	})
+	RegisterAfterHook(OpCreate, func(ctx context.Context, change *Change[testTask]) error {
+		tx, ok := TxFromContext(ctx)
+		ctx.Value(hookRecordKey{}).(*hookRecord).afterInTx = ok
+		// The row written is visible to the hook, which runs in the transaction of the write.
+		var n int64
+		if err := tx.Model(&testTask{}).Where("id = ?", change.New[0].ID).Count(&n).Error; err != nil || n != 1 {
+			return errors.New("write not visible to after hook")
+		}
+		if change.New[0].Title == "undo" {
+			return errHookRejected
+		}
+		return nil
+	})
+	RegisterBeforeHook(OpDelete, func(ctx context.Context, change *Change[testTask]) error {
+		record := ctx.Value(hookRecordKey{}).(*hookRecord)
+		for _, task := range change.Old {
+			record.deleted = append(record.deleted, task.Title)
+		}
+		return nil
+	})
+})
+
+func TestStoreHooks(t *testing.T) {
+	registerTaskHooks()
+	record := &hookRecord{}
+	ctx := context.WithValue(context.Background(), hookRecordKey{}, record)
+	s := NewStore[testTask](NewReadWriteProvider(newTestDB(t, &testTask{}), nil), nil, WithTenantExemption[testTask]())
+
+	count := func() int64 {
+		t.Helper()
+		n, err := s.Count(ctx, where.NewWhere())
+		require.NoError(t, err)
+		return n
+	}
+
+	assert.ErrorIs(t, s.Create(ctx, &testTask{}), errHookRejected)
+	assert.Zero(t, count(), "a before hook error aborts the write")
+
+	require.NoError(t, s.Create(ctx, &testTask{Title: "write tests"}))
+	assert.True(t, record.afterInTx)
+	assert.Equal(t, int64(1), count())
+
+	assert.ErrorIs(t, s.Create(ctx, &testTask{Title: "undo"}), errHookRejected)
+	assert.Equal(t, int64(1), count(), "an after hook error rolls the write back")
+
+	require.NoError(t, s.Delete(ctx, where.F("title", "write tests")))
+	assert.Equal(t, []string{"write tests"}, record.deleted)
+}
+
+func TestStoreAudit(t *testing.T) {
+	ctx := context.Background()
+	provider := NewReadWriteProvider(newTestDB(t, &testProfile{}, &AuditLog{}), nil)
+	operator := uint32(7)
+	s := NewStore[testProfile](provider, nil, WithTenantExemption[testProfile](), WithAudit[testProfile](Audit{
+		OperatorFunc: func(context.Context) (uint32, bool) { return operator, true },
+	}))
+	logs := NewAuditStore(provider, nil)
+
+	profile := &testProfile{Name: "alice", Age: 30}
+	require.NoError(t, s.Create(ctx, profile))
+	profile.Age = 31
+	require.NoError(t, s.Update(ctx, profile))
+	require.NoError(t, s.UpdateColumns(ctx, where.F("id", profile.ID), map[string]any{"name": "alicia"}))
+	require.NoError(t, s.Delete(ctx, where.F("id", profile.ID)))
+
+	_, entries, err := logs.List(ctx, where.S("id"))
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	for _, entry := range entries {
+		assert.Equal(t, "test_profiles", entry.Table)
+		assert.Equal(t, "1", entry.RecordID)
+		require.NotNil(t, entry.Operator)
+		assert.Equal(t, operator, *entry.Operator)
+	}
+
+	// Values are read back from their JSON encoding, where numbers are float64.
+	assert.Equal(t, OpCreate, entries[0].Action)
+	assert.Equal(t, map[string]FieldChange{
+		"id":   {New: float64(1)},
+		"name": {New: "alice"},
+		"age":  {New: float64(30)},
+	}, entries[0].Changes)
+
+	// Updates only record the columns that changed.
+	assert.Equal(t, OpUpdate, entries[1].Action)
+	assert.Equal(t, map[string]FieldChange{"age": {Old: float64(30), New: float64(31)}}, entries[1].Changes)
+	assert.Equal(t, OpUpdate, entries[2].Action)
+	assert.Equal(t, map[string]FieldChange{"name": {Old: "alice", New: "alicia"}}, entries[2].Changes)
+
+	assert.Equal(t, OpDelete, entries[3].Action)
+	assert.Equal(t, map[string]FieldChange{
+		"id":   {Old: float64(1)},
+		"name": {Old: "alicia"},
+		"age":  {Old: float64(31)},
+	}, entries[3].Changes)
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"iter"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// defaultIterateBatchSize is the number of rows Iterate reads per query when the where
+// options do not set a positive limit.
+const defaultIterateBatchSize = 500
+
+// Iterate returns an iterator over all the objects matching opts, for exports and batch jobs
+// on result sets too large for List. Objects are read with keyset pagination in batches of
+// opts.Limit rows (500 when not positive), so that at most one batch is held in memory, and
+// are ordered like ListPage. The iteration starts after opts.Cursor when set; opts.Offset,
+// opts.Backward and opts.SkipCount are ignored and no COUNT query is run.
+//
+// The iterator yields a nil object and the error when a query fails or ctx is canceled,
+// then stops. Results are never cached.
+//
+//	for user, err := range userStore.Iterate(ctx, where.F("status", "active")) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (s *Store[T]) Iterate(ctx context.Context, opts *where.Options) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := s.onShard(ctx, opts, func(ctx context.Context) error {
+			return s.iterate(ctx, opts, yield)
+		})
+		if err != nil {
+			s.logger.Error(ctx, err, "Failed to iterate objects from database", "conditions", opts)
+			yield(nil, err)
+		}
+	}
+}
+
+// iterate implements Iterate. It returns nil as soon as yield asks to stop.
+func (s *Store[T]) iterate(ctx context.Context, opts *where.Options, yield func(*T, error) bool) error {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	columns, err := s.orderColumns(sch, opts.Sorts)
+	if err != nil {
+		return err
+	}
+	if columns, err = keysetColumns(sch, columns); err != nil {
+		return err
+	}
+
+	batchSize := opts.Limit
+	if batchSize <= 0 {
+		batchSize = defaultIterateBatchSize
+	}
+	var after []any
+	if opts.Cursor != "" {
+		if after, err = where.DecodeCursor(opts.Cursor, cursorSort(sch, columns)); err != nil {
+			return err
+		}
+		if len(after) != len(columns) {
+			return where.ErrInvalidCursor
+		}
+	}
+
+	rowOpts := selectKeyset(opts, columns)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		db, err := s.withRelations(sch, s.readDB(ctx, opts).Offset(-1).Limit(batchSize), rowOpts)
+		if err != nil {
+			return err
+		}
+		if after != nil {
+			db = db.Where(keysetCondition(columns, after, false))
+		}
+		var batch []*T
+		if err := db.Clauses(orderBy(columns, false)).Find(&batch).Error; err != nil {
+			return err
+		}
+
+		for _, obj := range batch {
+			if !yield(obj, nil) {
+				return nil
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		if after, err = keysetValues(ctx, sch, columns, batch[len(batch)-1]); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+var (
+	// queryPattern matches the simple comparisons supported in string queries, e.g.
+	// "age >= ?", "name IN (?)" or "deleted_at IS NULL".
+	queryPattern = regexp.MustCompile(
+		"(?i)^\\s*[`\"]?([\\w.]+)[`\"]?\\s*(=|!=|<>|>=|<=|>|<|NOT\\s+LIKE|LIKE|NOT\\s+IN|IN|IS\\s+NOT\\s+NULL|IS\\s+NULL)\\s*(\\(\\s*\\?\\s*\\)|\\?)?\\s*$")
+	// andPattern splits string queries into their conditions.
+	andPattern = regexp.MustCompile(`(?i)\s+AND\s+`)
+	// spacePattern matches the spaces inside operators.
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// matches reports whether row satisfies the filters, clauses and queries of opts.
+func (s *Store[T]) matches(ctx context.Context, row *T, opts *where.Options) (bool, error) {
+	rv := reflect.ValueOf(row)
+	for key, value := range opts.Filters {
+		column, ok := key.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: filter key %v", ErrUnsupported, key)
+		}
+		if ok, err := s.compare(ctx, rv, column, "=", value); !ok || err != nil {
+			return false, err
+		}
+	}
+	for _, expr := range opts.Clauses {
+		if ok, err := s.evalClause(ctx, rv, expr); !ok || err != nil {
+			return false, err
+		}
+	}
+	for _, query := range opts.Queries {
+		if ok, err := s.evalQuery(ctx, rv, query.Query, query.Args); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// evalQuery evaluates a where.Query condition against rv. String queries must be
+// conjunctions of simple comparisons.
+func (s *Store[T]) evalQuery(ctx context.Context, rv reflect.Value, query any, args []any) (bool, error) {
+	switch q := query.(type) {
+	case string:
+		return s.evalSQL(ctx, rv, q, args)
+	case map[string]any:
+		for column, value := range q {
+			if ok, err := s.compare(ctx, rv, column, "=", value); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case clause.Expression:
+		return s.evalClause(ctx, rv, q)
+	default:
+		return false, fmt.Errorf("%w: query %T", ErrUnsupported, query)
+	}
+}
+
+// evalSQL evaluates a conjunction of simple comparisons with placeholders bound to args.
+func (s *Store[T]) evalSQL(ctx context.Context, rv reflect.Value, query string, args []any) (bool, error) {
+	for _, cond := range andPattern.Split(strings.TrimSpace(query), -1) {
+		m := queryPattern.FindStringSubmatch(cond)
+		if m == nil {
+			return false, fmt.Errorf("%w: query %q", ErrUnsupported, query)
+		}
+
+		op := strings.ToUpper(spacePattern.ReplaceAllString(m[2], " "))
+		var value any
+		switch op {
+		case "IS NULL", "IS NOT NULL":
+			if m[3] != "" {
+				return false, fmt.Errorf("%w: query %q", ErrUnsupported, query)
+			}
+		default:
+			if m[3] == "" || len(args) == 0 {
+				return false, fmt.Errorf("%w: query %q", ErrUnsupported, query)
+			}
+			value, args = args[0], args[1:]
+		}
+		if ok, err := s.compare(ctx, rv, m[1], op, value); !ok || err != nil {
+			return false, err
+		}
+	}
+	if len(args) > 0 {
+		return false, fmt.Errorf("%w: query %q has more arguments than placeholders", ErrUnsupported, query)
+	}
+	return true, nil
+}
+
+// evalClause evaluates the GORM clause expression expr against rv.
+func (s *Store[T]) evalClause(ctx context.Context, rv reflect.Value, expr clause.Expression) (bool, error) {
+	switch e := expr.(type) {
+	case clause.Eq:
+		return s.compareColumn(ctx, rv, e.Column, "=", e.Value)
+	case clause.Neq:
+		return s.compareColumn(ctx, rv, e.Column, "<>", e.Value)
+	case clause.Gt:
+		return s.compareColumn(ctx, rv, e.Column, ">", e.Value)
+	case clause.Gte:
+		return s.compareColumn(ctx, rv, e.Column, ">=", e.Value)
+	case clause.Lt:
+		return s.compareColumn(ctx, rv, e.Column, "<", e.Value)
+	case clause.Lte:
+		return s.compareColumn(ctx, rv, e.Column, "<=", e.Value)
+	case clause.Like:
+		return s.compareColumn(ctx, rv, e.Column, "LIKE", e.Value)
+	case clause.IN:
+		return s.compareColumn(ctx, rv, e.Column, "IN", e.Values)
+	case clause.Expr:
+		return s.evalSQL(ctx, rv, e.SQL, e.Vars)
+	case clause.AndConditions:
+		for _, sub := range e.Exprs {
+			if ok, err := s.evalClause(ctx, rv, sub); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case clause.OrConditions:
+		for _, sub := range e.Exprs {
+			if ok, err := s.evalClause(ctx, rv, sub); ok || err != nil {
+				return ok, err
+			}
+		}
+		return len(e.Exprs) == 0, nil
+	case clause.NotConditions:
+		for _, sub := range e.Exprs {
+			if ok, err := s.evalClause(ctx, rv, sub); ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: clause %T", ErrUnsupported, expr)
+	}
+}
+
+// compareColumn is like compare for the column of a clause, given as clause.Column or string.
+func (s *Store[T]) compareColumn(ctx context.Context, rv reflect.Value, column any, op string, value any) (bool, error) {
+	switch c := column.(type) {
+	case clause.Column:
+		return s.compare(ctx, rv, c.Name, op, value)
+	case string:
+		return s.compare(ctx, rv, c, op, value)
+	default:
+		return false, fmt.Errorf("%w: column %T", ErrUnsupported, column)
+	}
+}
+
+// compare applies the SQL comparison op between the column of rv and value, with SQL NULL
+// semantics. Slice values compare like IN, as GORM does for equality conditions.
+func (s *Store[T]) compare(ctx context.Context, rv reflect.Value, column, op string, value any) (bool, error) {
+	if idx := strings.LastIndexByte(column, '.'); idx >= 0 {
+		column = column[idx+1:]
+	}
+	field := s.schema.LookUpField(column)
+	if field == nil || field.DBName == "" {
+		return false, fmt.Errorf("%w: unknown column %s", ErrUnsupported, column)
+	}
+	fieldValue, _ := field.ValueOf(ctx, rv)
+	actual := normalize(fieldValue)
+
+	switch op {
+	case "IS NULL":
+		return actual == nil, nil
+	case "IS NOT NULL":
+		return actual != nil, nil
+	}
+
+	values, isList := inValues(value)
+	if !isList && (op == "IN" || op == "NOT IN") {
+		values, isList = []any{value}, true
+	}
+	if isList && (op == "=" || op == "IN" || op == "<>" || op == "!=" || op == "NOT IN") {
+		in := false
+		for _, v := range values {
+			if c, ok := compareValues(actual, normalize(v)); ok && c == 0 {
+				in = true
+				break
+			}
+		}
+		if op == "=" || op == "IN" {
+			return in, nil
+		}
+		return actual != nil && !in, nil
+	}
+
+	expected := normalize(value)
+	if actual == nil || expected == nil {
+		// Like in SQL, comparisons with NULL never hold; Eq with a nil value means IS NULL.
+		return op == "=" && actual == nil && expected == nil, nil
+	}
+	switch op {
+	case "LIKE", "NOT LIKE":
+		str, ok1 := actual.(string)
+		pattern, ok2 := expected.(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return likeMatch(str, pattern) == (op == "LIKE"), nil
+	}
+
+	c, ok := compareValues(actual, expected)
+	if !ok {
+		return false, nil
+	}
+	switch op {
+	case "=":
+		return c == 0, nil
+	case "<>", "!=":
+		return c != 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	default:
+		return false, fmt.Errorf("%w: operator %s", ErrUnsupported, op)
+	}
+}
+
+// inValues returns the elements of value if it is a slice or an array other than bytes.
+func inValues(value any) ([]any, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// likeMatch reports whether str matches the SQL LIKE pattern, case-insensitively like the
+// default collations of MySQL.
+func likeMatch(str, pattern string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			expr.WriteString(".*")
+		case '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(str)
+}
+
+// normalize converts v to nil, int64, uint64, float64, string, bool, time.Time or, for other
+// types, v itself, dereferencing pointers and resolving driver.Valuer implementations.
+func normalize(v any) any {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return v
+		}
+		if value == nil {
+			return nil
+		}
+		v = value
+	}
+	if t, ok := v.(time.Time); ok {
+		return t
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes())
+		}
+	}
+	return v
+}
+
+// compareValues compares normalized values, reporting false if they are not comparable.
+func compareValues(a, b any) (int, bool) {
+	switch av := a.(type) {
+	case int64:
+		switch bv := b.(type) {
+		case int64:
+			return cmp.Compare(av, bv), true
+		case uint64:
+			if av < 0 {
+				return -1, true
+			}
+			return cmp.Compare(uint64(av), bv), true
+		case float64:
+			return cmp.Compare(float64(av), bv), true
+		}
+	case uint64:
+		switch bv := b.(type) {
+		case uint64:
+			return cmp.Compare(av, bv), true
+		case int64:
+			if bv < 0 {
+				return 1, true
+			}
+			return cmp.Compare(av, uint64(bv)), true
+		case float64:
+			return cmp.Compare(float64(av), bv), true
+		}
+	case float64:
+		switch bv := b.(type) {
+		case float64:
+			return cmp.Compare(av, bv), true
+		case int64:
+			return cmp.Compare(av, float64(bv)), true
+		case uint64:
+			return cmp.Compare(av, float64(bv)), true
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0, true
+			case !av:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Compare(bv), true
+		}
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0, true
+	}
+	return 0, false
+}
+
+// orderValues orders raw field values for sorting, NULLs first like MySQL and SQLite do in
+// ascending order.
+func orderValues(a, b any) int {
+	a, b = normalize(a), normalize(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	c, _ := compareValues(a, b)
+	return c
+}
@@ -0,0 +1,374 @@
+// Package memory provides an in-memory implementation of store.IStore for unit tests of code
+// built on top of store.Store, without a database.
+//
+// The model is parsed like GORM does, so that where.Options filters, queries and sort
+// specifications reference the same columns as with store.Store, and errors follow the same
+// semantics: gorm.ErrRecordNotFound from Get, gorm.ErrMissingWhereClause from unconditional
+// deletes, store.ErrInvalidSort for unknown sort columns and errorsx.ErrVersionConflict for
+// models embedding mixin.Version. Tenant isolation, data scopes, soft delete, hooks, audit and
+// caching are not emulated.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/errorsx"
+	"github.com/moweilong/mo/store"
+	"github.com/moweilong/mo/store/mixin"
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrUnsupported is returned for where options the in-memory store cannot evaluate, such as
+// raw SQL queries other than simple comparisons, the filter DSL of where.FromFilterJSON,
+// associations or aggregates.
+var ErrUnsupported = errors.New("unsupported by the in-memory store")
+
+// Option configures a Store.
+type Option func(*options)
+
+// options holds the configuration of a Store.
+type options struct {
+	namer schema.Namer
+}
+
+// WithNamingStrategy sets the naming strategy used to derive column names, which must match
+// the one of the GORM configuration of the real store. Defaults to schema.NamingStrategy{}.
+func WithNamingStrategy(namer schema.Namer) Option {
+	return func(o *options) {
+		o.namer = namer
+	}
+}
+
+// Store is an in-memory store of objects of type T, safe for concurrent use. It stores copies
+// of the objects it is given and returns copies of the objects it holds.
+type Store[T any] struct {
+	mu     sync.RWMutex
+	schema *schema.Schema
+	err    error
+	rows   map[string]*T
+	nextID int64
+}
+
+var _ store.IStore[struct{}] = (*Store[struct{}])(nil)
+
+// NewStore creates an empty in-memory store of objects of type T.
+func NewStore[T any](opts ...Option) *Store[T] {
+	o := &options{namer: schema.NamingStrategy{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &Store[T]{rows: map[string]*T{}}
+	s.schema, s.err = schema.Parse(new(T), &sync.Map{}, o.namer)
+	if s.err == nil && len(s.schema.PrimaryFields) == 0 {
+		s.err = gorm.ErrPrimaryKeyRequired
+	}
+	return s
+}
+
+// Create inserts a copy of obj. A zero integer primary key is assigned the next ID, and
+// automatic timestamps and default values are set like GORM does.
+func (s *Store[T]) Create(ctx context.Context, obj *T) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rv := reflect.ValueOf(obj)
+	if err := s.assignPrimaryKey(ctx, rv); err != nil {
+		return err
+	}
+	key := s.primaryKey(ctx, obj)
+	if _, ok := s.rows[key]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+
+	now := time.Now()
+	for _, field := range s.schema.Fields {
+		if _, zero := field.ValueOf(ctx, rv); !zero {
+			continue
+		}
+		switch {
+		case field.AutoCreateTime > 0 || field.AutoUpdateTime > 0:
+			if err := field.Set(ctx, rv, now); err != nil {
+				return err
+			}
+		case field.DefaultValueInterface != nil:
+			if err := field.Set(ctx, rv, field.DefaultValueInterface); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.rows[key] = clone(obj)
+	return nil
+}
+
+// Update replaces the stored object with a copy of obj, or inserts it when it does not exist
+// yet, like gorm.DB.Save. Objects implementing mixin.Versioned are only replaced if their
+// version matches the stored one, and get their version incremented.
+func (s *Store[T]) Update(ctx context.Context, obj *T) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	versioned, isVersioned := any(obj).(mixin.Versioned)
+	if !isVersioned {
+		if s.primaryKeyZero(ctx, obj) {
+			return s.Create(ctx, obj)
+		}
+	} else if s.primaryKeyZero(ctx, obj) {
+		return gorm.ErrPrimaryKeyRequired
+	}
+
+	s.mu.Lock()
+	key := s.primaryKey(ctx, obj)
+	old, exists := s.rows[key]
+	if isVersioned {
+		if !exists || any(old).(mixin.Versioned).GetVersion() != versioned.GetVersion() {
+			s.mu.Unlock()
+			return errorsx.ErrVersionConflict
+		}
+		versioned.SetVersion(versioned.GetVersion() + 1)
+	}
+	if !exists {
+		s.mu.Unlock()
+		return s.Create(ctx, obj)
+	}
+	defer s.mu.Unlock()
+
+	rv := reflect.ValueOf(obj)
+	for _, field := range s.schema.Fields {
+		if field.AutoUpdateTime > 0 {
+			if err := field.Set(ctx, rv, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+	s.rows[key] = clone(obj)
+	return nil
+}
+
+// Delete removes the objects matching opts, regardless of its pagination. Like GORM, it
+// refuses to run without any condition.
+func (s *Store[T]) Delete(ctx context.Context, opts *where.Options) error {
+	if s.err != nil {
+		return s.err
+	}
+	if opts == nil || len(opts.Filters) == 0 && len(opts.Clauses) == 0 && len(opts.Queries) == 0 {
+		return gorm.ErrMissingWhereClause
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, row := range s.rows {
+		ok, err := s.matches(ctx, row, opts)
+		if err != nil {
+			return err
+		}
+		if ok {
+			delete(s.rows, key)
+		}
+	}
+	return nil
+}
+
+// Get returns a copy of the first object matching opts, in the order of its sort
+// specifications or by ascending primary key, or gorm.ErrRecordNotFound.
+func (s *Store[T]) Get(ctx context.Context, opts *where.Options) (*T, error) {
+	sorts := opts.Sorts
+	if len(sorts) == 0 {
+		for _, field := range s.schemaPrimaryFields() {
+			sorts = append(sorts, where.Sort{Column: field.DBName})
+		}
+	}
+
+	objs, err := s.find(ctx, opts, sorts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Offset > 0 {
+		objs = objs[min(opts.Offset, len(objs)):]
+	}
+	if len(objs) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return objs[0], nil
+}
+
+// List returns copies of the objects matching opts, in the order of its sort specifications
+// or by descending primary key, and their count regardless of the pagination. count is left
+// as zero when counting is disabled in opts.
+func (s *Store[T]) List(ctx context.Context, opts *where.Options) (count int64, ret []*T, err error) {
+	sorts := opts.Sorts
+	if len(sorts) == 0 && s.schema != nil && s.schema.PrioritizedPrimaryField != nil {
+		sorts = []where.Sort{{Column: s.schema.PrioritizedPrimaryField.DBName, Desc: true}}
+	}
+
+	objs, err := s.find(ctx, opts, sorts)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !opts.SkipCount {
+		count = int64(len(objs))
+	}
+	if opts.Offset > 0 {
+		objs = objs[min(opts.Offset, len(objs)):]
+	}
+	if opts.Limit >= 0 {
+		objs = objs[:min(opts.Limit, len(objs))]
+	}
+	return count, objs, nil
+}
+
+// find returns copies of the objects matching opts ordered by sorts, ignoring the pagination.
+func (s *Store[T]) find(ctx context.Context, opts *where.Options, sorts []where.Sort) ([]*T, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(opts.Preloads) > 0 || len(opts.Joined) > 0 || len(opts.GroupBy) > 0 || len(opts.Having) > 0 {
+		return nil, fmt.Errorf("%w: associations and aggregates", ErrUnsupported)
+	}
+	fields := make([]*schema.Field, 0, len(sorts))
+	for _, srt := range sorts {
+		field := s.schema.LookUpField(srt.Column)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w: %s", store.ErrInvalidSort, srt.Column)
+		}
+		fields = append(fields, field)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	objs := make([]*T, 0, len(s.rows))
+	for _, row := range s.rows {
+		ok, err := s.matches(ctx, row, opts)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			objs = append(objs, clone(row))
+		}
+	}
+
+	slices.SortStableFunc(objs, func(a, b *T) int {
+		for i, field := range fields {
+			av, _ := field.ValueOf(ctx, reflect.ValueOf(a))
+			bv, _ := field.ValueOf(ctx, reflect.ValueOf(b))
+			if c := orderValues(av, bv); c != 0 {
+				if sorts[i].Desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return cmp.Compare(s.primaryKey(ctx, a), s.primaryKey(ctx, b))
+	})
+
+	if len(opts.Selects) > 0 {
+		for _, obj := range objs {
+			if err := s.selectColumns(ctx, obj, opts.Selects); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return objs, nil
+}
+
+// selectColumns zeroes the columns of obj that are not selected, except the primary key.
+func (s *Store[T]) selectColumns(ctx context.Context, obj *T, selects []string) error {
+	selected := map[string]struct{}{}
+	for _, col := range selects {
+		field := s.schema.LookUpField(col)
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("%w: %s", store.ErrInvalidColumn, col)
+		}
+		selected[field.DBName] = struct{}{}
+	}
+
+	rv := reflect.ValueOf(obj)
+	for _, field := range s.schema.Fields {
+		if _, ok := selected[field.DBName]; ok || field.PrimaryKey || field.DBName == "" {
+			continue
+		}
+		fv := field.ReflectValueOf(ctx, rv)
+		fv.Set(reflect.Zero(fv.Type()))
+	}
+	return nil
+}
+
+// assignPrimaryKey assigns the next ID to the zero integer primary key of rv.
+func (s *Store[T]) assignPrimaryKey(ctx context.Context, rv reflect.Value) error {
+	field := s.schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+	value, zero := field.ValueOf(ctx, rv)
+	switch id := normalize(value).(type) {
+	case int64:
+		s.nextID = max(s.nextID, id)
+	case uint64:
+		s.nextID = max(s.nextID, int64(id))
+	default:
+		return nil
+	}
+	if !zero {
+		return nil
+	}
+	s.nextID++
+	return field.Set(ctx, rv, s.nextID)
+}
+
+// primaryKey formats the primary key of obj, padding integers so that keys sort numerically.
+func (s *Store[T]) primaryKey(ctx context.Context, obj *T) string {
+	keys := make([]string, 0, len(s.schema.PrimaryFields))
+	for _, field := range s.schema.PrimaryFields {
+		value, _ := field.ValueOf(ctx, reflect.ValueOf(obj))
+		switch v := normalize(value).(type) {
+		case int64:
+			keys = append(keys, fmt.Sprintf("%020d", v))
+		case uint64:
+			keys = append(keys, fmt.Sprintf("%020d", v))
+		default:
+			keys = append(keys, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// primaryKeyZero reports whether a field of the primary key of obj is zero.
+func (s *Store[T]) primaryKeyZero(ctx context.Context, obj *T) bool {
+	for _, field := range s.schema.PrimaryFields {
+		if _, zero := field.ValueOf(ctx, reflect.ValueOf(obj)); zero {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaPrimaryFields returns the primary key fields, if the model could be parsed.
+func (s *Store[T]) schemaPrimaryFields() []*schema.Field {
+	if s.schema == nil {
+		return nil
+	}
+	return s.schema.PrimaryFields
+}
+
+// clone returns a shallow copy of obj.
+func clone[T any](obj *T) *T {
+	c := *obj
+	return &c
+}
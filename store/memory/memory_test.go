@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/errorsx"
+	"github.com/moweilong/mo/gormx"
+	"github.com/moweilong/mo/store"
+	"github.com/moweilong/mo/store/mixin"
+	"github.com/moweilong/mo/store/where"
+)
+
+type testUser struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"size:64"`
+	Age       int
+	Email     *string `gorm:"size:64"`
+	CreatedAt time.Time
+	mixin.Version
+}
+
+// stores returns a database-backed store and an in-memory store of testUser, each seeded with
+// the same users.
+func stores(t *testing.T) map[string]store.IStore[testUser] {
+	t.Helper()
+
+	db, err := gormx.NewSQLite(&gormx.SQLiteOptions{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&testUser{}))
+
+	impls := map[string]store.IStore[testUser]{
+		"database": store.NewStore[testUser](store.NewReadWriteProvider(db, nil), nil),
+		"memory":   NewStore[testUser](),
+	}
+	email := "carol@example.com"
+	for _, s := range impls {
+		for _, user := range []*testUser{
+			{Name: "alice", Age: 30},
+			{Name: "bob", Age: 25},
+			{Name: "carol", Age: 35, Email: &email},
+			{Name: "dave", Age: 25},
+		} {
+			require.NoError(t, s.Create(context.Background(), user))
+		}
+	}
+	return impls
+}
+
+func names(users []*testUser) []string {
+	ret := make([]string, 0, len(users))
+	for _, user := range users {
+		ret = append(ret, user.Name)
+	}
+	return ret
+}
+
+func TestStoreParity(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("create", func(t *testing.T) {
+				user, err := s.Get(ctx, where.F("name", "alice"))
+				require.NoError(t, err)
+				assert.Equal(t, uint(1), user.ID)
+				assert.Equal(t, int64(1), user.Version.Version)
+				assert.False(t, user.CreatedAt.IsZero())
+			})
+
+			t.Run("list", func(t *testing.T) {
+				count, users, err := s.List(ctx, where.NewWhere())
+				require.NoError(t, err)
+				assert.Equal(t, int64(4), count)
+				assert.Equal(t, []string{"dave", "carol", "bob", "alice"}, names(users))
+
+				count, users, err = s.List(ctx, where.S("age", "-name").O(1).L(2))
+				require.NoError(t, err)
+				assert.Equal(t, int64(4), count)
+				assert.Equal(t, []string{"bob", "alice"}, names(users))
+
+				count, users, err = s.List(ctx, where.P(2, 3).S("name").NoCount())
+				require.NoError(t, err)
+				assert.Zero(t, count)
+				assert.Equal(t, []string{"dave"}, names(users))
+
+				_, _, err = s.List(ctx, where.S("unknown"))
+				assert.ErrorIs(t, err, store.ErrInvalidSort)
+			})
+
+			t.Run("conditions", func(t *testing.T) {
+				tests := []struct {
+					name string
+					opts *where.Options
+					want []string
+				}{
+					{"filter", where.F("age", 25), []string{"bob", "dave"}},
+					{"filter in", where.F("name", []string{"alice", "carol", "eve"}), []string{"alice", "carol"}},
+					{"filter null", where.F("email", nil), []string{"alice", "bob", "dave"}},
+					{"query", where.NewWhere().Q("age > ? AND name <> ?", 25, "carol"), []string{"alice"}},
+					{"query in", where.NewWhere().Q("name IN ?", []string{"bob", "dave"}), []string{"bob", "dave"}},
+					{"query like", where.NewWhere().Q("name LIKE ?", "%a%e"), []string{"alice", "dave"}},
+					{"query not null", where.NewWhere().Q("email IS NOT NULL"), []string{"carol"}},
+					{"clauses", where.C(clause.Or(clause.Eq{Column: "age", Value: 35}, clause.Lt{Column: clause.Column{Name: "id"}, Value: 2})), []string{"alice", "carol"}},
+					{"not", where.C(clause.Not(clause.Eq{Column: "age", Value: 25})), []string{"alice", "carol"}},
+				}
+				for _, tt := range tests {
+					t.Run(tt.name, func(t *testing.T) {
+						_, users, err := s.List(ctx, tt.opts.S("name"))
+						require.NoError(t, err)
+						assert.Equal(t, tt.want, names(users))
+					})
+				}
+			})
+
+			t.Run("get", func(t *testing.T) {
+				user, err := s.Get(ctx, where.F("age", 25))
+				require.NoError(t, err)
+				assert.Equal(t, "bob", user.Name)
+
+				user, err = s.Get(ctx, where.F("age", 25).S("-name"))
+				require.NoError(t, err)
+				assert.Equal(t, "dave", user.Name)
+
+				_, err = s.Get(ctx, where.F("name", "nobody"))
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			})
+
+			t.Run("update", func(t *testing.T) {
+				user, err := s.Get(ctx, where.F("name", "bob"))
+				require.NoError(t, err)
+				stale := *user
+
+				user.Age = 26
+				require.NoError(t, s.Update(ctx, user))
+				assert.Equal(t, int64(2), user.Version.Version)
+
+				stale.Age = 27
+				assert.ErrorIs(t, s.Update(ctx, &stale), errorsx.ErrVersionConflict)
+
+				user, err = s.Get(ctx, where.F("name", "bob"))
+				require.NoError(t, err)
+				assert.Equal(t, 26, user.Age)
+
+				assert.ErrorIs(t, s.Update(ctx, &testUser{Name: "nobody"}), gorm.ErrPrimaryKeyRequired)
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				assert.ErrorIs(t, s.Delete(ctx, where.NewWhere()), gorm.ErrMissingWhereClause)
+				require.NoError(t, s.Delete(ctx, where.F("name", "nobody")))
+				require.NoError(t, s.Delete(ctx, where.F("age", 25).L(1)))
+
+				_, users, err := s.List(ctx, where.S("name"))
+				require.NoError(t, err)
+				assert.Equal(t, []string{"alice", "bob", "carol"}, names(users))
+			})
+		})
+	}
+}
+
+func TestStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore[testUser]()
+
+	user := &testUser{Name: "alice"}
+	require.NoError(t, s.Create(ctx, user))
+	user.Name = "changed"
+
+	got, err := s.Get(ctx, where.F("id", user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.Name)
+	got.Name = "changed"
+
+	got, err = s.Get(ctx, where.F("id", user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.Name)
+
+	assert.ErrorIs(t, s.Create(ctx, &testUser{ID: user.ID}), gorm.ErrDuplicatedKey)
+
+	_, err = s.Get(ctx, where.NewWhere().Q("LOWER(name) = ?", "alice"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	// The filter DSL is not evaluated rather than matching the wrong objects.
+	dsl, err := where.FromFilterJSON(`{"name__icontains":"ALI"}`, "")
+	require.NoError(t, err)
+	_, err = s.Get(ctx, dsl)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.ErrorIs(t, s.Delete(ctx, dsl), ErrUnsupported)
+	assert.ErrorIs(t, s.Delete(ctx, nil), gorm.ErrMissingWhereClause)
+	_, err = s.Get(ctx, where.F("id", user.ID))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLockTimeout is returned when the migration lock could not be acquired in time.
+var ErrLockTimeout = errors.New("timed out waiting for migration lock")
+
+// lockRetryInterval is the interval between attempts to acquire a PostgreSQL advisory lock.
+const lockRetryInterval = 500 * time.Millisecond
+
+// lock acquires the migration lock on conn and returns the function releasing it. The lock is
+// a session-level named lock on MySQL and an advisory lock on PostgreSQL, both released when
+// the connection closes. Other databases, such as SQLite which serializes writers anyway,
+// are not locked.
+func (m *Migrator) lock(ctx context.Context, conn *gorm.DB) (func(), error) {
+	release := conn.WithContext(context.WithoutCancel(ctx))
+
+	switch conn.Dialector.Name() {
+	case "mysql":
+		var acquired *int
+		seconds := int(math.Ceil(m.lockTimeout.Seconds()))
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", m.lockName, seconds).Scan(&acquired).Error; err != nil {
+			return nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if acquired == nil || *acquired != 1 {
+			return nil, fmt.Errorf("%w: %s", ErrLockTimeout, m.lockName)
+		}
+		return func() { release.Exec("SELECT RELEASE_LOCK(?)", m.lockName) }, nil
+	case "postgres":
+		key := advisoryLockKey(m.lockName)
+		deadline := time.Now().Add(m.lockTimeout)
+		for {
+			var acquired bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&acquired).Error; err != nil {
+				return nil, fmt.Errorf("acquire migration lock: %w", err)
+			}
+			if acquired {
+				return func() { release.Exec("SELECT pg_advisory_unlock(?)", key) }, nil
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("%w: %s", ErrLockTimeout, m.lockName)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(lockRetryInterval):
+			}
+		}
+	default:
+		return func() {}, nil
+	}
+}
+
+// advisoryLockKey derives the PostgreSQL advisory lock key of name.
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
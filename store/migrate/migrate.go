@@ -0,0 +1,381 @@
+// Package migrate runs versioned schema migrations.
+//
+// Migrations are either Go functions or SQL files, applied in version order and recorded in
+// the schema_migrations table together with a checksum of their SQL, so that migrations
+// edited after being applied are detected. Every run holds a database-level lock, so that
+// several instances started at once do not apply the same migrations concurrently.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/log"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied SQL migration was modified afterwards.
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrIrreversible is returned when rolling back a migration that has no down step.
+	ErrIrreversible = errors.New("migration is irreversible")
+	// ErrUnknownMigration is returned when an applied migration to roll back is not registered.
+	ErrUnknownMigration = errors.New("unknown migration")
+	// ErrDuplicateVersion is returned when two migrations share the same version.
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+)
+
+// MigrateFunc applies or rolls back a migration using tx, which carries the context of the run.
+// Unless the migration is marked NoTx, tx is a transaction that also records the migration.
+type MigrateFunc func(tx *gorm.DB) error
+
+// Migration is a versioned schema change.
+type Migration struct {
+	// Version orders the migrations, e.g. 1, 2, 3 or timestamps such as 20240101120000.
+	Version int64
+	// Name describes the migration.
+	Name string
+	// Up applies the migration.
+	Up MigrateFunc
+	// Down rolls the migration back. Migrations without Down cannot be rolled back.
+	Down MigrateFunc
+	// NoTx runs the migration outside a transaction, for statements that cannot run in one,
+	// such as CREATE INDEX CONCURRENTLY on PostgreSQL.
+	NoTx bool
+
+	// checksum is the checksum of the SQL of SQL migrations.
+	checksum string
+}
+
+// AutoMigrate returns a Migration auto-migrating models, e.g. to create the initial schema of
+// the models registered in the store/registry package. It cannot be rolled back.
+func AutoMigrate(version int64, name string, models ...any) *Migration {
+	return &Migration{
+		Version: version,
+		Name:    name,
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(models...)
+		},
+	}
+}
+
+// SchemaMigration is the record of an applied migration.
+type SchemaMigration struct {
+	Version   int64     `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;size:255;not null"`
+	Checksum  string    `gorm:"column:checksum;size:64;not null;default:''"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+// State is the state of a migration reported by Status.
+type State string
+
+const (
+	// StatePending marks a registered migration that is not applied.
+	StatePending State = "pending"
+	// StateApplied marks an applied migration.
+	StateApplied State = "applied"
+	// StateModified marks an applied migration whose SQL changed since.
+	StateModified State = "modified"
+	// StateMissing marks an applied migration that is not registered.
+	StateMissing State = "missing"
+)
+
+// Status is the state of a migration.
+type Status struct {
+	Version   int64
+	Name      string
+	State     State
+	AppliedAt *time.Time
+}
+
+// Option configures a Migrator.
+type Option func(*Migrator)
+
+// WithTable sets the table recording the applied migrations. Defaults to schema_migrations.
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockName sets the name of the database lock held while migrating. Migrators sharing a
+// database but not their migrations, e.g. of different services, should use different names.
+// Defaults to the table name.
+func WithLockName(name string) Option {
+	return func(m *Migrator) {
+		m.lockName = name
+	}
+}
+
+// WithLockTimeout sets how long to wait for the database lock. Defaults to 1 minute.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// Migrator applies and rolls back migrations.
+type Migrator struct {
+	db          *gorm.DB
+	table       string
+	lockName    string
+	lockTimeout time.Duration
+	migrations  []*Migration
+}
+
+// NewMigrator creates a Migrator running migrations against db.
+func NewMigrator(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:          db,
+		table:       "schema_migrations",
+		lockTimeout: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.lockName == "" {
+		m.lockName = m.table
+	}
+	return m
+}
+
+// Add registers migrations.
+func (m *Migrator) Add(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d has no up step", migration.Version)
+		}
+		if m.find(migration.Version) != nil {
+			return fmt.Errorf("%w: %d", ErrDuplicateVersion, migration.Version)
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+	slices.SortFunc(m.migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return nil
+}
+
+// Up applies every pending migration, including those older than the latest applied one.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.UpTo(ctx, math.MaxInt64)
+}
+
+// UpTo applies the pending migrations up to version, like Up. Unlike To, it never rolls
+// back: nothing is done for the migrations above version, even if they were applied.
+func (m *Migrator) UpTo(ctx context.Context, version int64) error {
+	return m.run(ctx, func(conn *gorm.DB, applied map[int64]*SchemaMigration) error {
+		for _, migration := range m.migrations {
+			if migration.Version <= version && applied[migration.Version] == nil {
+				if err := m.apply(conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.run(ctx, func(conn *gorm.DB, applied map[int64]*SchemaMigration) error {
+		versions := appliedVersions(applied)
+		if len(versions) == 0 {
+			return nil
+		}
+		return m.rollback(conn, applied[versions[len(versions)-1]])
+	})
+}
+
+// To migrates to version: applied migrations above version are rolled back, latest first,
+// and pending migrations up to version are applied. Use version 0 to roll back all.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	return m.run(ctx, func(conn *gorm.DB, applied map[int64]*SchemaMigration) error {
+		versions := appliedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			if err := m.rollback(conn, applied[versions[i]]); err != nil {
+				return err
+			}
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= version && applied[migration.Version] == nil {
+				if err := m.apply(conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the state of every registered or applied migration, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.WithContext(ctx)
+	if err := m.ensureTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations)+len(applied))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name, State: StatePending}
+		if record := applied[migration.Version]; record != nil {
+			status.State = StateApplied
+			status.AppliedAt = &record.AppliedAt
+			if !checksumMatches(migration, record) {
+				status.State = StateModified
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		if m.find(record.Version) == nil {
+			statuses = append(statuses, Status{Version: record.Version, Name: record.Name, State: StateMissing, AppliedAt: &record.AppliedAt})
+		}
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return statuses, nil
+}
+
+// run holds the database lock on a single connection while running fn with the applied
+// migrations, after checking that none of them was modified.
+func (m *Migrator) run(ctx context.Context, fn func(conn *gorm.DB, applied map[int64]*SchemaMigration) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// The pinned connection is turned into a session, so that every step starts afresh.
+		conn = conn.Session(&gorm.Session{})
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, record := range applied {
+			if migration := m.find(record.Version); migration != nil && !checksumMatches(migration, record) {
+				return fmt.Errorf("%w: %d %s", ErrChecksumMismatch, record.Version, record.Name)
+			}
+		}
+		return fn(conn, applied)
+	})
+}
+
+// apply applies migration and records it.
+func (m *Migrator) apply(conn *gorm.DB, migration *Migration) error {
+	start := time.Now()
+	err := m.step(conn, migration.NoTx, migration.Up, func(tx *gorm.DB) error {
+		return tx.Table(m.table).Create(&SchemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.checksum,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+
+	log.Infow("Applied migration", "version", migration.Version, "name", migration.Name, "duration", time.Since(start))
+	return nil
+}
+
+// rollback rolls back the applied migration of record and removes the record.
+func (m *Migrator) rollback(conn *gorm.DB, record *SchemaMigration) error {
+	migration := m.find(record.Version)
+	if migration == nil {
+		return fmt.Errorf("%w: %d %s", ErrUnknownMigration, record.Version, record.Name)
+	}
+	if migration.Down == nil {
+		return fmt.Errorf("%w: %d %s", ErrIrreversible, migration.Version, migration.Name)
+	}
+
+	start := time.Now()
+	err := m.step(conn, migration.NoTx, migration.Down, func(tx *gorm.DB) error {
+		return tx.Table(m.table).Where("version = ?", record.Version).Delete(&SchemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("roll back migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+
+	log.Infow("Rolled back migration", "version", migration.Version, "name", migration.Name, "duration", time.Since(start))
+	return nil
+}
+
+// step runs fn followed by record, in a single transaction unless noTx is set.
+func (m *Migrator) step(conn *gorm.DB, noTx bool, fn, record MigrateFunc) error {
+	if noTx {
+		if err := fn(conn); err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+}
+
+// ensureTable creates the migrations table if needed.
+func (m *Migrator) ensureTable(db *gorm.DB) error {
+	return db.Table(m.table).AutoMigrate(&SchemaMigration{})
+}
+
+// applied returns the records of the applied migrations, keyed by version.
+func (m *Migrator) applied(db *gorm.DB) (map[int64]*SchemaMigration, error) {
+	var records []*SchemaMigration
+	if err := db.Table(m.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]*SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// find returns the registered migration of version, if any.
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// appliedVersions returns the versions of applied in ascending order.
+func appliedVersions(applied map[int64]*SchemaMigration) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// checksumMatches reports whether migration is unchanged since it was recorded. Go
+// migrations have no checksum and always match.
+func checksumMatches(migration *Migration, record *SchemaMigration) bool {
+	return migration.checksum == "" || record.Checksum == "" || migration.checksum == record.Checksum
+}
@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/gormx"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, err := gormx.NewSQLite(&gormx.SQLiteOptions{Logger: logger.Discard})
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"m/1_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);\nINSERT INTO users (name) VALUES ('a;b');")},
+		"m/1_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"m/3_email.up.sql":   {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"m/3_email.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	}
+	rename := &Migration{
+		Version: 2,
+		Name:    "rename",
+		Up:      func(tx *gorm.DB) error { return tx.Exec("UPDATE users SET name = 'go'").Error },
+		Down:    func(tx *gorm.DB) error { return tx.Exec("UPDATE users SET name = 'a;b'").Error },
+	}
+	newMigrator := func() *Migrator {
+		m := NewMigrator(db)
+		require.NoError(t, m.AddFS(fsys, "m"))
+		require.NoError(t, m.Add(rename))
+		return m
+	}
+	states := func(m *Migrator) []State {
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		ret := make([]State, 0, len(statuses))
+		for _, status := range statuses {
+			ret = append(ret, status.State)
+		}
+		return ret
+	}
+
+	m := newMigrator()
+	assert.Equal(t, []State{StatePending, StatePending, StatePending}, states(m))
+
+	require.NoError(t, m.Up(ctx))
+	assert.Equal(t, []State{StateApplied, StateApplied, StateApplied}, states(m))
+	var name string
+	require.NoError(t, db.Raw("SELECT name FROM users").Scan(&name).Error)
+	assert.Equal(t, "go", name)
+	assert.True(t, db.Migrator().HasColumn("users", "email"))
+
+	require.NoError(t, m.Down(ctx))
+	assert.False(t, db.Migrator().HasColumn("users", "email"))
+	assert.Equal(t, []State{StateApplied, StateApplied, StatePending}, states(m))
+
+	require.NoError(t, m.To(ctx, 0))
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	require.NoError(t, m.To(ctx, 1))
+	assert.Equal(t, []State{StateApplied, StatePending, StatePending}, states(m))
+
+	require.NoError(t, m.UpTo(ctx, 2))
+	assert.Equal(t, []State{StateApplied, StateApplied, StatePending}, states(m))
+	require.NoError(t, m.Up(ctx))
+	require.NoError(t, m.UpTo(ctx, 1), "already past the version")
+	assert.Equal(t, []State{StateApplied, StateApplied, StateApplied}, states(m))
+	require.NoError(t, m.To(ctx, 1))
+
+	t.Run("modified migration", func(t *testing.T) {
+		fsys["m/1_users.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id INTEGER);")}
+		modified := NewMigrator(db)
+		require.NoError(t, modified.AddFS(fsys, "m"))
+
+		assert.ErrorIs(t, modified.Up(ctx), ErrChecksumMismatch)
+		assert.Equal(t, []State{StateModified, StatePending}, states(modified))
+	})
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		failing := NewMigrator(db, WithTable("failing_migrations"))
+		require.NoError(t, failing.Add(&Migration{Version: 1, Name: "fail", Up: func(tx *gorm.DB) error {
+			require.NoError(t, tx.Exec("CREATE TABLE partial (id INTEGER)").Error)
+			return errors.New("boom")
+		}}))
+
+		assert.Error(t, failing.Up(ctx))
+		assert.False(t, db.Migrator().HasTable("partial"))
+		assert.Equal(t, []State{StatePending}, states(failing))
+	})
+
+	t.Run("irreversible migration", func(t *testing.T) {
+		baseline := NewMigrator(db, WithTable("baseline_migrations"))
+		require.NoError(t, baseline.Add(AutoMigrate(1, "baseline", &SchemaMigration{})))
+		require.NoError(t, baseline.Up(ctx))
+		assert.ErrorIs(t, baseline.Down(ctx), ErrIrreversible)
+	})
+}
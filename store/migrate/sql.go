@@ -0,0 +1,190 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqlFilePattern matches the names of SQL migration files, e.g. 0001_create_users.up.sql.
+var sqlFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// noTxDirective marks an SQL migration to run outside a transaction when found on a line of
+// its up file.
+const noTxDirective = "-- migrate:no-transaction"
+
+// AddFS registers the SQL migrations found in dir of fsys, typically an embed.FS:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	err := migrator.AddFS(migrations, "migrations")
+//
+// Migrations are made of a <version>_<name>.up.sql file and an optional
+// <version>_<name>.down.sql file. Files may hold several statements separated by semicolons.
+// Other files are ignored.
+func (m *Migrator) AddFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	migrations := map[int64]*Migration{}
+	var versions []int64
+	for _, entry := range entries {
+		matches := sqlFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse migration version of %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		migration := migrations[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = migration
+			versions = append(versions, version)
+		} else if migration.Name != matches[2] {
+			return fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		fn := execSQL(splitStatements(string(content)))
+		if matches[3] == "up" {
+			sum := sha256.Sum256(content)
+			migration.Up = fn
+			migration.checksum = hex.EncodeToString(sum[:])
+			migration.NoTx = hasDirective(string(content), noTxDirective)
+		} else {
+			migration.Down = fn
+		}
+	}
+
+	for _, version := range versions {
+		if migrations[version].Up == nil {
+			return fmt.Errorf("migration %d has no up file", version)
+		}
+		if err := m.Add(migrations[version]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execSQL returns a MigrateFunc executing statements in order.
+func execSQL(statements []string) MigrateFunc {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// hasDirective reports whether a line of content is directive.
+func hasDirective(content, directive string) bool {
+	for line := range strings.Lines(content) {
+		if strings.TrimSpace(line) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits content into statements at the semicolons found outside of quoted
+// strings, identifiers, comments and PostgreSQL dollar-quoted bodies. Statements made only
+// of comments are dropped.
+func splitStatements(content string) []string {
+	var (
+		statements []string
+		start      int
+		hasCode    bool
+	)
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(content[start:end]); hasCode && stmt != "" {
+			statements = append(statements, stmt)
+		}
+		start, hasCode = end+1, false
+	}
+
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '-' && strings.HasPrefix(content[i:], "--"):
+			i = skipTo(content, i+2, "\n") - 1
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			i = skipTo(content, i+2, "*/") - 1
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(content, i, c)
+			hasCode = true
+		case c == '$':
+			if tag := dollarTag(content[i:]); tag != "" {
+				i = skipTo(content, i+len(tag), tag) - 1
+			}
+			hasCode = true
+		case c == ';':
+			flush(i)
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+	}
+	if start < len(content) {
+		flush(len(content))
+	}
+	return statements
+}
+
+// skipTo returns the index following the first occurrence of end in content at or after
+// from, or len(content) if there is none.
+func skipTo(content string, from int, end string) int {
+	if idx := strings.Index(content[from:], end); idx >= 0 {
+		return from + idx + len(end)
+	}
+	return len(content)
+}
+
+// skipQuoted returns the index of the quote closing the string opened at start, taking
+// doubled and backslash-escaped quotes into account.
+func skipQuoted(content string, start int, quote byte) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(content) && content[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(content)
+}
+
+// dollarTag returns the dollar-quote tag content starts with, e.g. "$$" or "$body$".
+func dollarTag(content string) string {
+	for i := 1; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '$':
+			return content[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
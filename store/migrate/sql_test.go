@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "single statement without semicolon",
+			content: "CREATE TABLE users (id INT)",
+			want:    []string{"CREATE TABLE users (id INT)"},
+		},
+		{
+			name:    "several statements",
+			content: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			want:    []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:    "semicolons in strings and identifiers",
+			content: "INSERT INTO t VALUES ('a;b', 'it''s;');\nSELECT \"x;y\", `z;w` FROM t;",
+			want:    []string{"INSERT INTO t VALUES ('a;b', 'it''s;')", "SELECT \"x;y\", `z;w` FROM t"},
+		},
+		{
+			name:    "comments",
+			content: "-- create; the table\nCREATE TABLE a (id INT); /* done; */\n-- trailing comment;\n",
+			want:    []string{"-- create; the table\nCREATE TABLE a (id INT)"},
+		},
+		{
+			name: "dollar-quoted body",
+			content: "CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n  NEW.x := 1;\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;\n" +
+				"SELECT $$a;b$$;",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n  NEW.x := 1;\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql",
+				"SELECT $$a;b$$",
+			},
+		},
+		{
+			name:    "positional parameters are not dollar quotes",
+			content: "SELECT $1; SELECT 2;",
+			want:    []string{"SELECT $1", "SELECT 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitStatements(tt.content))
+		})
+	}
+}
+
+func TestAddFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/0003_index.up.sql":          {Data: []byte("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx ON users (email);")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+
+	m := NewMigrator(nil)
+	require.NoError(t, m.AddFS(fsys, "migrations"))
+	require.Len(t, m.migrations, 3)
+
+	assert.Equal(t, int64(1), m.migrations[0].Version)
+	assert.Equal(t, "create_users", m.migrations[0].Name)
+	assert.NotNil(t, m.migrations[0].Down)
+	assert.Len(t, m.migrations[0].checksum, 64)
+
+	assert.Equal(t, "add_email", m.migrations[1].Name)
+	assert.Nil(t, m.migrations[1].Down)
+	assert.False(t, m.migrations[1].NoTx)
+	assert.NotEqual(t, m.migrations[0].checksum, m.migrations[1].checksum)
+
+	assert.True(t, m.migrations[2].NoTx)
+
+	t.Run("duplicate version", func(t *testing.T) {
+		err := m.Add(&Migration{Version: 2, Name: "again", Up: execSQL(nil)})
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+
+	t.Run("down without up", func(t *testing.T) {
+		fsys := fstest.MapFS{"m/0001_x.down.sql": {Data: []byte("SELECT 1;")}}
+		assert.Error(t, NewMigrator(nil).AddFS(fsys, "m"))
+	})
+}
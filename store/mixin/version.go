@@ -0,0 +1,31 @@
+package mixin
+
+// VersionColumn is the column holding the optimistic locking version.
+const VersionColumn = "version"
+
+// Versioned is implemented by models that use optimistic locking.
+type Versioned interface {
+	// GetVersion returns the version the model was read with.
+	GetVersion() int64
+	// SetVersion sets the version of the model.
+	SetVersion(version int64)
+}
+
+var _ Versioned = (*Version)(nil)
+
+// Version is a GORM model mixin that adds an optimistic locking version column.
+// Embed it into a model to make store.Store.Update fail with a version conflict
+// instead of overwriting concurrent changes.
+type Version struct {
+	Version int64 `gorm:"column:version;not null;default:1" json:"version"`
+}
+
+// GetVersion returns the version the model was read with.
+func (v *Version) GetVersion() int64 {
+	return v.Version
+}
+
+// SetVersion sets the version of the model.
+func (v *Version) SetVersion(version int64) {
+	v.Version = version
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrInvalidSort is returned when a sort specification references an unknown or non-sortable column.
+var ErrInvalidSort = errors.New("invalid sort column")
+
+// orderColumn is a resolved column of a query ordering.
+type orderColumn struct {
+	Name string
+	Desc bool
+}
+
+// WithSortableColumns returns an Option that restricts the columns where.Options sort
+// specifications may reference. Columns can be given as column or field names.
+// Without it, any column of the model is sortable.
+func WithSortableColumns[T any](columns ...string) Option[T] {
+	return func(s *Store[T]) {
+		s.sortable = make(map[string]struct{}, len(columns))
+		for _, col := range columns {
+			s.sortable[col] = struct{}{}
+		}
+	}
+}
+
+// orderColumns resolves the sort specifications against the model schema. Only real,
+// whitelisted columns are accepted, so user-provided sort keys cannot inject SQL.
+// Without sort specifications, it falls back to the primary key in descending order.
+func (s *Store[T]) orderColumns(sch *schema.Schema, sorts []where.Sort) ([]orderColumn, error) {
+	columns := make([]orderColumn, 0, len(sorts))
+	for _, srt := range sorts {
+		field := sch.LookUpField(srt.Column)
+		if field == nil || field.DBName == "" || !s.isSortable(srt.Column, field) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSort, srt.Column)
+		}
+		columns = append(columns, orderColumn{Name: field.DBName, Desc: srt.Desc})
+	}
+
+	if len(columns) == 0 && sch.PrioritizedPrimaryField != nil {
+		columns = append(columns, orderColumn{Name: sch.PrioritizedPrimaryField.DBName, Desc: true})
+	}
+	return columns, nil
+}
+
+// isSortable reports whether the column referenced as name is allowed in sort specifications.
+func (s *Store[T]) isSortable(name string, field *schema.Field) bool {
+	if s.sortable == nil {
+		return true
+	}
+	for _, key := range []string{name, field.DBName, field.Name} {
+		if _, ok := s.sortable[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// orderBy builds the ORDER BY clause for columns, optionally reversing every direction.
+func orderBy(columns []orderColumn, reverse bool) clause.OrderBy {
+	expr := clause.OrderBy{}
+	for _, col := range columns {
+		expr.Columns = append(expr.Columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: col.Name},
+			Desc:   col.Desc != reverse,
+		})
+	}
+	return expr
+}
@@ -0,0 +1,221 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ListPage is a page of objects returned by Store.ListPage.
+type ListPage[T any] struct {
+	// Items holds the objects of the page in query order.
+	Items []*T `json:"items"`
+	// Total is the number of objects matching the conditions, regardless of the cursor.
+	// It is left as zero when counting is disabled in the where options.
+	Total int64 `json:"total"`
+	// NextCursor selects the following page with Options.After. It is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// PrevCursor selects the preceding page with Options.Before. It is empty on the first page.
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// ListPage retrieves a page of objects using keyset (cursor) pagination.
+// The page starts after or before the cursor set on opts and holds at most opts.Limit objects;
+// opts.Offset is ignored. Rows are ordered like List, with the primary key appended as a
+// tie-breaker so that the ordering is total.
+func (s *Store[T]) ListPage(ctx context.Context, opts *where.Options) (*ListPage[T], error) {
+	var page *ListPage[T]
+	err := s.onShard(ctx, opts, func(ctx context.Context) (err error) {
+		page, err = s.listPage(ctx, opts)
+		return err
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to list page of objects from database", "conditions", opts)
+		return nil, err
+	}
+	return page, nil
+}
+
+// listPage implements ListPage.
+func (s *Store[T]) listPage(ctx context.Context, opts *where.Options) (*ListPage[T], error) {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := s.orderColumns(sch, opts.Sorts)
+	if err != nil {
+		return nil, err
+	}
+	if columns, err = keysetColumns(sch, columns); err != nil {
+		return nil, err
+	}
+
+	page := &ListPage[T]{}
+	if !opts.SkipCount {
+		if err := s.count(ctx, sch, opts, &page.Total); err != nil {
+			return nil, err
+		}
+	}
+
+	backward := opts.Backward && opts.Cursor != ""
+	db, err := s.withRelations(sch, s.readDB(ctx, opts).Offset(-1), selectKeyset(opts, columns))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cursor != "" {
+		values, err := where.DecodeCursor(opts.Cursor, cursorSort(sch, columns))
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != len(columns) {
+			return nil, where.ErrInvalidCursor
+		}
+		db = db.Where(keysetCondition(columns, values, backward))
+	}
+
+	db = db.Clauses(orderBy(columns, backward))
+	if opts.Limit > 0 {
+		// Fetch one extra row to find out whether another page exists.
+		db = db.Limit(opts.Limit + 1)
+	}
+
+	if err := db.Find(&page.Items).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := opts.Limit > 0 && len(page.Items) > opts.Limit
+	if hasMore {
+		page.Items = page.Items[:opts.Limit]
+	}
+	hasNext, hasPrev := hasMore, opts.Cursor != ""
+	if backward {
+		slices.Reverse(page.Items)
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if len(page.Items) == 0 {
+		return page, nil
+	}
+	if hasNext {
+		if page.NextCursor, err = cursorOf(ctx, sch, columns, page.Items[len(page.Items)-1]); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = cursorOf(ctx, sch, columns, page.Items[0]); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// schema returns the parsed GORM schema of T.
+func (s *Store[T]) schema(ctx context.Context) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: s.db(ctx)}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// selectKeyset returns opts with the keyset columns added to its column selection, if any,
+// since cursors are made of the sort-key values and must always be read.
+func selectKeyset(opts *where.Options, columns []orderColumn) *where.Options {
+	if len(opts.Selects) == 0 {
+		return opts
+	}
+	copied := *opts
+	copied.Selects = slices.Clone(opts.Selects)
+	for _, col := range columns {
+		copied.Selects = append(copied.Selects, col.Name)
+	}
+	return &copied
+}
+
+// keysetColumns appends the primary key to columns unless it is already part of them,
+// making the ordering total as keyset pagination requires.
+func keysetColumns(sch *schema.Schema, columns []orderColumn) ([]orderColumn, error) {
+	pk := sch.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, errors.New("keyset pagination requires a primary key")
+	}
+	for _, col := range columns {
+		if col.Name == pk.DBName {
+			return columns, nil
+		}
+	}
+	return append(columns, orderColumn{Name: pk.DBName, Desc: columns[len(columns)-1].Desc}), nil
+}
+
+// keysetCondition builds the condition selecting the rows after (or, when backward, before)
+// the row whose sort-key values are values:
+//
+//	(c1 > v1) OR (c1 = v1 AND c2 > v2) OR ...
+//
+// where the comparison is reversed for descending columns.
+func keysetCondition(columns []orderColumn, values []any, backward bool) clause.Expression {
+	ors := make([]clause.Expression, 0, len(columns))
+	for i, col := range columns {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: columns[j].Name}, Value: values[j]})
+		}
+
+		column := clause.Column{Table: clause.CurrentTable, Name: col.Name}
+		if col.Desc == backward {
+			ands = append(ands, clause.Gt{Column: column, Value: values[i]})
+		} else {
+			ands = append(ands, clause.Lt{Column: column, Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
+
+// cursorOf encodes the sort-key values of obj into a cursor.
+func cursorOf(ctx context.Context, sch *schema.Schema, columns []orderColumn, obj any) (string, error) {
+	values, err := keysetValues(ctx, sch, columns, obj)
+	if err != nil {
+		return "", err
+	}
+	return where.EncodeCursor(cursorSort(sch, columns), values...)
+}
+
+// cursorSort identifies the model and ordering of keyset cursors, e.g. "users:-created_at,id",
+// so that a cursor is rejected by queries of another model or with other sort specifications.
+func cursorSort(sch *schema.Schema, columns []orderColumn) string {
+	var b strings.Builder
+	b.WriteString(sch.Table)
+	b.WriteByte(':')
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if col.Desc {
+			b.WriteByte('-')
+		}
+		b.WriteString(col.Name)
+	}
+	return b.String()
+}
+
+// keysetValues returns the sort-key values of obj.
+func keysetValues(ctx context.Context, sch *schema.Schema, columns []orderColumn, obj any) ([]any, error) {
+	values := make([]any, len(columns))
+	for i, col := range columns {
+		field := sch.LookUpField(col.Name)
+		if field == nil {
+			return nil, errors.New("unknown keyset column: " + col.Name)
+		}
+		values[i], _ = field.ValueOf(ctx, reflect.ValueOf(obj))
+	}
+	return values, nil
+}
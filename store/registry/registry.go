@@ -35,6 +35,24 @@ func (r *Registry) Register(model interface{}) {
 	r.models = append(r.models, model)
 }
 
+// Models 返回全局注册的所有模型，可用于 migrate.AutoMigrate 生成基线迁移
+func Models() []interface{} {
+	if globalRegistry == nil {
+		return nil
+	}
+
+	return globalRegistry.Models()
+}
+
+// Models 返回Registry中注册的所有模型
+func (r *Registry) Models() []interface{} {
+	return append([]interface{}(nil), r.models...)
+}
+
+// Migrate 对全局注册的所有模型执行 AutoMigrate
+//
+// Deprecated: AutoMigrate 无法删除或重命名列、迁移数据或回滚，请使用 store/migrate 包的版本化迁移，
+// 例如 migrate.AutoMigrate(1, "baseline", registry.Models()...)。
 func Migrate(db *gorm.DB) error {
 	if globalRegistry == nil {
 		return nil
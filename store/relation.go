@@ -0,0 +1,90 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrInvalidRelation is returned when a preload or join references an unknown association.
+var ErrInvalidRelation = errors.New("invalid relation")
+
+// withJoins applies the joined associations of opts to db. It is used by every query
+// reading rows of T, including counts, so that conditions on joined columns apply alike.
+func (s *Store[T]) withJoins(sch *schema.Schema, db *gorm.DB, opts *where.Options) (*gorm.DB, error) {
+	for _, join := range opts.Joined {
+		if err := validateRelation(sch, join.Name, false); err != nil {
+			return nil, err
+		}
+		db = db.Joins(join.Name, join.Conds...)
+	}
+	return db, nil
+}
+
+// withRelations applies the joined and preloaded associations and the column selection of
+// opts to db, for queries returning rows of T.
+func (s *Store[T]) withRelations(sch *schema.Schema, db *gorm.DB, opts *where.Options) (*gorm.DB, error) {
+	db, err := s.withJoins(sch, db, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, preload := range opts.Preloads {
+		if err := validateRelation(sch, preload.Name, true); err != nil {
+			return nil, err
+		}
+		db = db.Preload(preload.Name, preload.Conds...)
+	}
+	if len(opts.Selects) == 0 {
+		return db, nil
+	}
+
+	// Columns are qualified so that they stay unambiguous next to joined tables, and the
+	// primary key is always read since preloads and updates of the results rely on it.
+	// Sharded queries already read a shard table, by which the columns are qualified then.
+	table := sch.Table
+	if db.Statement.Table != "" {
+		table = db.Statement.Table
+	}
+	columns := make([]string, 0, len(opts.Selects)+len(sch.PrimaryFields))
+	seen := make(map[string]struct{}, cap(columns))
+	addColumn := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			columns = append(columns, db.Statement.Quote(clause.Column{Table: table, Name: name}))
+		}
+	}
+	for _, field := range sch.PrimaryFields {
+		addColumn(field.DBName)
+	}
+	for _, col := range opts.Selects {
+		name, err := columnName(sch, col)
+		if err != nil {
+			return nil, err
+		}
+		addColumn(name)
+	}
+	return db.Select(columns), nil
+}
+
+// validateRelation checks that every segment of the dotted association path name is an
+// association of the model. A preload path may end with clause.Associations.
+func validateRelation(sch *schema.Schema, name string, preload bool) error {
+	segments := strings.Split(name, ".")
+	for i, segment := range segments {
+		if preload && segment == clause.Associations && i == len(segments)-1 {
+			return nil
+		}
+		rel, ok := sch.Relationships.Relations[segment]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidRelation, name)
+		}
+		sch = rel.FieldSchema
+	}
+	return nil
+}
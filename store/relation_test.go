@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+func TestStoreRelations(t *testing.T) {
+	ctx := context.Background()
+	employees, db := newEmployeeStore(t)
+	companies := NewStore[testCompany](NewReadWriteProvider(db, nil), nil, WithTenantExemption[testCompany]())
+
+	t.Run("preload", func(t *testing.T) {
+		_, list, err := companies.List(ctx, where.Preload("Employees").S("id"))
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Len(t, list[0].Employees, 2)
+		assert.Len(t, list[1].Employees, 1)
+
+		company, err := companies.Get(ctx, where.Preload("Employees", "age > ?", 35).F("name", "acme"))
+		require.NoError(t, err)
+		require.Len(t, company.Employees, 1)
+		assert.Equal(t, "bob", company.Employees[0].Name)
+	})
+
+	t.Run("joined", func(t *testing.T) {
+		_, list, err := employees.List(ctx, where.Joins("Company").Q("Company.name = ?", "acme").S("name"))
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		for _, e := range list {
+			require.NotNil(t, e.Company)
+			assert.Equal(t, "acme", e.Company.Name)
+		}
+		assert.Equal(t, "alice", list[0].Name)
+
+		// Joined queries are counted with the same conditions.
+		total, _, err := employees.List(ctx, where.Joins("Company").Q("Company.name = ?", "initech"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+	})
+
+	t.Run("select", func(t *testing.T) {
+		_, list, err := employees.List(ctx, where.Select("name").S("id"))
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		assert.Equal(t, testEmployee{ID: list[0].ID, Name: "alice"}, *list[0])
+		assert.NotZero(t, list[0].ID, "the primary key is always read")
+
+		// Selected columns stay unambiguous next to joined tables.
+		e, err := employees.Get(ctx, where.Joins("Company").Select("name").Q("Company.name = ?", "initech"))
+		require.NoError(t, err)
+		assert.Equal(t, "carol", e.Name)
+		assert.Zero(t, e.Age)
+
+		// The primary key read along with the selection lets the preload find the associations.
+		company, err := companies.Get(ctx, where.Select("name").Preload("Employees").F("name", "acme"))
+		require.NoError(t, err)
+		assert.Len(t, company.Employees, 2)
+
+		_, _, err = employees.List(ctx, where.Select("unknown"))
+		assert.ErrorIs(t, err, ErrInvalidColumn)
+	})
+
+	t.Run("invalid relation", func(t *testing.T) {
+		_, _, err := employees.List(ctx, where.Joins("Manager"))
+		assert.ErrorIs(t, err, ErrInvalidRelation)
+		_, err = companies.Get(ctx, where.Preload("Employees.Manager").F("id", 1))
+		assert.ErrorIs(t, err, ErrInvalidRelation)
+		_, _, err = companies.List(ctx, where.Preload(clause.Associations))
+		assert.NoError(t, err)
+	})
+}
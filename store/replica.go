@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ReadDBProvider is implemented by DBProviders that can serve queries from read replicas.
+// Store uses ReadDB for Get, List and ListPage, and DB for writes and transactions.
+type ReadDBProvider interface {
+	DBProvider
+	// ReadDB returns the database instance used for read-only queries.
+	ReadDB(ctx context.Context, wheres ...where.Where) *gorm.DB
+}
+
+// primaryKey is the context key that forces queries onto the primary.
+type primaryKey struct{}
+
+// WithPrimary returns a copy of ctx whose queries are served by the primary instead of
+// a replica. Use it to read your own writes when replication lag is not acceptable.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryForced reports whether queries of ctx must be served by the primary.
+func PrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// ReplicaPolicy picks the replica serving the next query among n healthy ones.
+type ReplicaPolicy func(n int) int
+
+// RoundRobinPolicy returns a ReplicaPolicy that cycles through the replicas.
+func RoundRobinPolicy() ReplicaPolicy {
+	var next atomic.Uint64
+	return func(n int) int {
+		return int((next.Add(1) - 1) % uint64(n))
+	}
+}
+
+// RandomPolicy returns a ReplicaPolicy that picks a replica at random.
+func RandomPolicy() ReplicaPolicy {
+	return func(n int) int {
+		return rand.IntN(n)
+	}
+}
+
+// ReadWriteOption configures a ReadWriteProvider.
+type ReadWriteOption func(*ReadWriteProvider)
+
+// WithReplicaPolicy sets the load-balancing policy across replicas. Defaults to RoundRobinPolicy.
+func WithReplicaPolicy(policy ReplicaPolicy) ReadWriteOption {
+	return func(p *ReadWriteProvider) {
+		p.policy = policy
+	}
+}
+
+// WithHealthCheck sets how often replicas are pinged and how long a ping may take.
+// A replica failing a ping is ejected until a later ping succeeds. A zero interval
+// disables health checks. Defaults to 10s and 2s.
+func WithHealthCheck(interval, timeout time.Duration) ReadWriteOption {
+	return func(p *ReadWriteProvider) {
+		p.checkInterval = interval
+		p.checkTimeout = timeout
+	}
+}
+
+// replica is a read replica together with its health.
+type replica struct {
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+// ReadWriteProvider is a DBProvider that sends writes and transactions to a primary and
+// spreads queries across healthy read replicas, falling back to the primary when none is healthy.
+type ReadWriteProvider struct {
+	primary  *gorm.DB
+	replicas []*replica
+	policy   ReplicaPolicy
+
+	checkInterval time.Duration
+	checkTimeout  time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ ReadDBProvider = (*ReadWriteProvider)(nil)
+
+// NewReadWriteProvider creates a ReadWriteProvider and, unless disabled, starts checking the
+// health of the replicas in the background. Call Close to stop the health checks.
+func NewReadWriteProvider(primary *gorm.DB, replicas []*gorm.DB, opts ...ReadWriteOption) *ReadWriteProvider {
+	p := &ReadWriteProvider{
+		primary:       primary,
+		replicas:      make([]*replica, 0, len(replicas)),
+		policy:        RoundRobinPolicy(),
+		checkInterval: 10 * time.Second,
+		checkTimeout:  2 * time.Second,
+		stop:          make(chan struct{}),
+	}
+	for _, db := range replicas {
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		p.replicas = append(p.replicas, r)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.checkInterval > 0 && len(p.replicas) > 0 {
+		go p.healthLoop()
+	}
+	return p
+}
+
+// DB returns the primary.
+func (p *ReadWriteProvider) DB(ctx context.Context, wheres ...where.Where) *gorm.DB {
+	return p.primary.WithContext(ctx)
+}
+
+// ReadDB returns a healthy replica chosen by the replica policy, or the primary when
+// ctx is marked with WithPrimary or no replica is healthy.
+func (p *ReadWriteProvider) ReadDB(ctx context.Context, wheres ...where.Where) *gorm.DB {
+	if PrimaryForced(ctx) {
+		return p.DB(ctx, wheres...)
+	}
+
+	healthy := make([]*replica, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return p.DB(ctx, wheres...)
+	}
+	return healthy[p.policy(len(healthy))].db.WithContext(ctx)
+}
+
+// CheckHealth pings every replica once, ejecting the failing ones and restoring the recovered ones.
+func (p *ReadWriteProvider) CheckHealth(ctx context.Context) {
+	for _, r := range p.replicas {
+		r.healthy.Store(p.ping(ctx, r.db) == nil)
+	}
+}
+
+// Close stops the background health checks. It does not close the database connections.
+func (p *ReadWriteProvider) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// healthLoop runs CheckHealth every check interval until Close is called.
+func (p *ReadWriteProvider) healthLoop() {
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.CheckHealth(context.Background())
+		}
+	}
+}
+
+// ping checks that db is reachable within the check timeout.
+func (p *ReadWriteProvider) ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if p.checkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.checkTimeout)
+		defer cancel()
+	}
+	return sqlDB.PingContext(ctx)
+}
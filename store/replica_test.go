@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+func TestReadWriteProvider(t *testing.T) {
+	ctx := context.Background()
+	// Every database holds a single user named after it, to tell where a query went.
+	open := func(name string) *gorm.DB {
+		db := newTestDB(t, &testUser{})
+		require.NoError(t, db.Create(&testUser{Name: name}).Error)
+		return db
+	}
+	primary, replica1, replica2 := open("primary"), open("replica1"), open("replica2")
+	p := NewReadWriteProvider(primary, []*gorm.DB{replica1, replica2}, WithHealthCheck(0, 20*time.Millisecond))
+	defer p.Close()
+	s := NewStore[testUser](p, nil, WithTenantExemption[testUser]())
+
+	// served returns the database serving a query, with a single query per call.
+	served := func(ctx context.Context) string {
+		t.Helper()
+		_, users, err := s.List(ctx, where.NewWhere(where.WithoutCount()))
+		require.NoError(t, err)
+		require.NotEmpty(t, users)
+		return users[0].Name
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		assert.Equal(t, []string{"replica1", "replica2", "replica1"}, []string{served(ctx), served(ctx), served(ctx)})
+		assert.Equal(t, "primary", served(WithPrimary(ctx)))
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		require.NoError(t, s.Create(ctx, &testUser{Name: "written"}))
+		var n int64
+		require.NoError(t, primary.Model(&testUser{}).Where("name = ?", "written").Count(&n).Error)
+		assert.Equal(t, int64(1), n)
+		require.NoError(t, replica1.Model(&testUser{}).Where("name = ?", "written").Count(&n).Error)
+		assert.Zero(t, n)
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		require.NoError(t, s.Tx(ctx, func(ctx context.Context) error {
+			// Reads inside a transaction see its own writes on the primary.
+			assert.Equal(t, "written", served(ctx))
+			return nil
+		}))
+	})
+
+	t.Run("health", func(t *testing.T) {
+		// In-memory databases have a single connection: holding it makes pings time out.
+		sqlDB, err := replica1.DB()
+		require.NoError(t, err)
+		conn, err := sqlDB.Conn(ctx)
+		require.NoError(t, err)
+
+		p.CheckHealth(ctx)
+		assert.Equal(t, []string{"replica2", "replica2"}, []string{served(ctx), served(ctx)})
+
+		require.NoError(t, conn.Close())
+		p.CheckHealth(ctx)
+		seen := map[string]bool{served(ctx): true, served(ctx): true}
+		assert.Equal(t, map[string]bool{"replica1": true, "replica2": true}, seen)
+	})
+
+	t.Run("no healthy replica", func(t *testing.T) {
+		var conns []interface{ Close() error }
+		for _, db := range []*gorm.DB{replica1, replica2} {
+			sqlDB, err := db.DB()
+			require.NoError(t, err)
+			conn, err := sqlDB.Conn(ctx)
+			require.NoError(t, err)
+			conns = append(conns, conn)
+		}
+		p.CheckHealth(ctx)
+		assert.Equal(t, "written", served(ctx))
+		for _, conn := range conns {
+			require.NoError(t, conn.Close())
+		}
+	})
+}
@@ -0,0 +1,659 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+var (
+	// ErrMissingShardingKey is returned when the sharding key of a sharded model cannot be
+	// determined from an object or from the where options of a query.
+	ErrMissingShardingKey = errors.New("missing sharding key")
+	// ErrTooManyShards is returned when a query spans more shards than Sharding.MaxFanOut.
+	ErrTooManyShards = errors.New("query spans too many shards")
+	// ErrCrossShard is returned by the operations that run on a single shard, such as
+	// ListPage and Aggregate, when the where options do not select exactly one shard.
+	ErrCrossShard = errors.New("query does not select a single shard")
+)
+
+// defaultMaxFanOut is the number of shards a query may span when Sharding.MaxFanOut is not positive.
+const defaultMaxFanOut = 16
+
+// ShardRange is a range of sharding key values. A nil bound leaves the range unbounded on that side.
+type ShardRange struct {
+	// Min is the inclusive lower bound.
+	Min any
+	// Max is the upper bound, inclusive unless MaxExclusive is set.
+	Max          any
+	MaxExclusive bool
+}
+
+// ShardingStrategy maps the sharding key values of a model to its physical tables.
+type ShardingStrategy interface {
+	// Table returns the table, derived from the model table base, holding the rows whose
+	// sharding key is value.
+	Table(base string, value any) (string, error)
+	// Tables returns, in ascending order, the tables that may hold rows whose sharding key
+	// is within r. Strategies that cannot route ranges return ErrMissingShardingKey.
+	Tables(base string, r ShardRange) ([]string, error)
+}
+
+// HashSharding spreads rows over Shards tables, named base_0 to base_<Shards-1>, by the
+// hash of their sharding key modulo Shards. Integer keys are taken as their own hash.
+type HashSharding struct {
+	Shards int
+}
+
+var _ ShardingStrategy = HashSharding{}
+
+// Table implements ShardingStrategy.
+func (h HashSharding) Table(base string, value any) (string, error) {
+	if h.Shards <= 0 {
+		return "", fmt.Errorf("invalid number of hash shards: %d", h.Shards)
+	}
+	value = indirectValue(value)
+	if value == nil {
+		return "", ErrMissingShardingKey
+	}
+
+	var shard uint64
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.CanInt():
+		n := int64(h.Shards)
+		shard = uint64((rv.Int()%n + n) % n)
+	case rv.CanUint():
+		shard = rv.Uint() % uint64(h.Shards)
+	default:
+		sum := fnv.New64a()
+		if b, ok := value.([]byte); ok {
+			_, _ = sum.Write(b)
+		} else {
+			_, _ = fmt.Fprint(sum, value)
+		}
+		shard = sum.Sum64() % uint64(h.Shards)
+	}
+	return base + "_" + strconv.FormatUint(shard, 10), nil
+}
+
+// Tables implements ShardingStrategy. Hashes do not preserve order, so ranges are not routed.
+func (h HashSharding) Tables(string, ShardRange) ([]string, error) {
+	return nil, fmt.Errorf("%w: hash sharding cannot route a range of keys", ErrMissingShardingKey)
+}
+
+// AllTables returns the names of all the tables of base, e.g. to migrate them.
+func (h HashSharding) AllTables(base string) []string {
+	tables := make([]string, 0, h.Shards)
+	for i := range h.Shards {
+		tables = append(tables, base+"_"+strconv.Itoa(i))
+	}
+	return tables
+}
+
+// MonthSharding stores the rows of each calendar month in its own table, named base_YYYYMM,
+// by a time.Time sharding key such as created_at. Months are those of Location, or of
+// time.Local when nil.
+type MonthSharding struct {
+	Location *time.Location
+}
+
+var _ ShardingStrategy = MonthSharding{}
+
+// Table implements ShardingStrategy.
+func (m MonthSharding) Table(base string, value any) (string, error) {
+	t, err := m.month(value)
+	if err != nil {
+		return "", err
+	}
+	return base + "_" + t.Format("200601"), nil
+}
+
+// Tables implements ShardingStrategy. Both bounds of r are required.
+func (m MonthSharding) Tables(base string, r ShardRange) ([]string, error) {
+	if r.Min == nil || r.Max == nil {
+		return nil, fmt.Errorf("%w: month sharding requires a bounded time range", ErrMissingShardingKey)
+	}
+	from, err := m.month(r.Min)
+	if err != nil {
+		return nil, err
+	}
+	to, err := m.month(r.Max)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaxExclusive && to.Equal(indirectValue(r.Max).(time.Time)) {
+		// The range ends right before the month of Max.
+		to = to.AddDate(0, -1, 0)
+	}
+
+	var tables []string
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		tables = append(tables, base+"_"+month.Format("200601"))
+	}
+	return tables, nil
+}
+
+// month returns the start of the month of value, a time.Time.
+func (m MonthSharding) month(value any) (time.Time, error) {
+	t, ok := indirectValue(value).(time.Time)
+	if !ok || t.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: month sharding requires a time, got %v", ErrMissingShardingKey, value)
+	}
+	loc := m.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc), nil
+}
+
+// Sharding describes how the rows of a model are split horizontally over several tables.
+type Sharding struct {
+	// Column is the sharding key, as column or field name, e.g. "user_id".
+	Column string
+	// Strategy maps sharding key values to tables.
+	Strategy ShardingStrategy
+	// MaxFanOut is the maximum number of shards a query may span. Defaults to 16.
+	MaxFanOut int
+}
+
+// WithSharding returns an Option that routes every operation of the Store to the physical
+// tables holding the rows, as chosen by sh.Strategy from the sharding key. Objects being
+// written must have their sharding key set, except for a zero auto-create time such as
+// CreatedAt, which is set to the current time first. The sharding key of an object must
+// not be changed by Update. The where options of the other operations must constrain the
+// sharding key through Filters or clause.Eq, clause.IN, clause.Gt, clause.Gte, clause.Lt
+// and clause.Lte conditions in Clauses, or the operation fails with ErrMissingShardingKey.
+// SQL conditions added with Q and the filter DSL conditions of where.FromFilterJSON never
+// select shards, even on the sharding key; they only filter the rows of the shards selected
+// by the other conditions.
+//
+// List, Get, Count and Exists merge the results of up to sh.MaxFanOut shards, in the order
+// of the where options; UpdateColumns, Delete, HardDelete and Restore, as well as writes of
+// objects of several shards, run in a single transaction across shards. Pluck, Aggregate,
+// ListPage and Iterate require a single shard. The tables must exist; see
+// HashSharding.AllTables and MonthSharding.Tables to migrate them.
+func WithSharding[T any](sh Sharding) Option[T] {
+	return func(s *Store[T]) {
+		if sh.MaxFanOut <= 0 {
+			sh.MaxFanOut = defaultMaxFanOut
+		}
+		s.sharding = &sh
+	}
+}
+
+// shardTableKey is the context key of the shard table of model T chosen for an operation.
+type shardTableKey[T any] struct{}
+
+// withShard returns a copy of ctx routing the operations on T to table.
+func withShard[T any](ctx context.Context, table string) context.Context {
+	return context.WithValue(ctx, shardTableKey[T]{}, table)
+}
+
+// shardFromContext returns the shard table of T carried by ctx.
+func shardFromContext[T any](ctx context.Context) (string, bool) {
+	table, ok := ctx.Value(shardTableKey[T]{}).(string)
+	return table, ok
+}
+
+// shardingField returns the schema and the sharding key field of the model.
+func (s *Store[T]) shardingField(ctx context.Context) (*schema.Schema, *schema.Field, error) {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	field := sch.LookUpField(s.sharding.Column)
+	if field == nil || field.DBName == "" {
+		return nil, nil, fmt.Errorf("%w: sharding key %s", ErrInvalidColumn, s.sharding.Column)
+	}
+	return sch, field, nil
+}
+
+// shardOf returns the shard table of obj.
+func (s *Store[T]) shardOf(ctx context.Context, sch *schema.Schema, field *schema.Field, obj *T) (string, error) {
+	rv := reflect.ValueOf(obj)
+	value, zero := field.ValueOf(ctx, rv)
+	if zero && field.AutoCreateTime > 0 {
+		if err := field.Set(ctx, rv, time.Now()); err != nil {
+			return "", err
+		}
+		value, zero = field.ValueOf(ctx, rv)
+	}
+	if zero {
+		return "", fmt.Errorf("%w: %s of %s is not set", ErrMissingShardingKey, field.Name, sch.Name)
+	}
+	return s.sharding.Strategy.Table(sch.Table, value)
+}
+
+// onObjectShards groups objs by shard and runs write once per shard with the objects of the
+// shard, in a transaction when there are several. Unsharded Stores run write once with objs.
+func (s *Store[T]) onObjectShards(ctx context.Context, objs []*T, write func(ctx context.Context, objs []*T) error) error {
+	if s.sharding == nil {
+		return write(ctx, objs)
+	}
+	sch, field, err := s.shardingField(ctx)
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	groups := map[string][]*T{}
+	for _, obj := range objs {
+		table, err := s.shardOf(ctx, sch, field, obj)
+		if err != nil {
+			return err
+		}
+		if _, ok := groups[table]; !ok {
+			tables = append(tables, table)
+		}
+		groups[table] = append(groups[table], obj)
+	}
+	if len(tables) == 1 {
+		return write(withShard[T](ctx, tables[0]), objs)
+	}
+	return runTx(ctx, s.storage, func(ctx context.Context) error {
+		for _, table := range tables {
+			if err := write(withShard[T](ctx, table), groups[table]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// shards returns the shard tables matching opts, at most Sharding.MaxFanOut of them.
+func (s *Store[T]) shards(ctx context.Context, opts *where.Options) ([]string, error) {
+	sch, field, err := s.shardingField(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values, r, found := shardingConditions(opts, field)
+	var tables []string
+	switch {
+	case values != nil:
+		for _, value := range values {
+			table, err := s.sharding.Strategy.Table(sch.Table, value)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+		slices.Sort(tables)
+	case found:
+		if tables, err = s.sharding.Strategy.Tables(sch.Table, r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: no condition on %s", ErrMissingShardingKey, field.DBName)
+	}
+
+	if len(tables) > s.sharding.MaxFanOut {
+		return nil, fmt.Errorf("%w: %d shards, at most %d", ErrTooManyShards, len(tables), s.sharding.MaxFanOut)
+	}
+	return tables, nil
+}
+
+// onShards runs fn once per shard matching opts, in a transaction when there are several.
+// Unsharded Stores run fn once.
+func (s *Store[T]) onShards(ctx context.Context, opts *where.Options, fn func(ctx context.Context) error) error {
+	if s.sharding == nil {
+		return fn(ctx)
+	}
+	tables, err := s.shards(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 1 {
+		return fn(withShard[T](ctx, tables[0]))
+	}
+	return runTx(ctx, s.storage, func(ctx context.Context) error {
+		for _, table := range tables {
+			if err := fn(withShard[T](ctx, table)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readShards runs fn once per shard matching opts, outside of any transaction, stopping at
+// the first error. Unsharded Stores run fn once.
+func (s *Store[T]) readShards(ctx context.Context, opts *where.Options, fn func(ctx context.Context) error) error {
+	if s.sharding == nil {
+		return fn(ctx)
+	}
+	tables, err := s.shards(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := fn(withShard[T](ctx, table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onShard runs fn on the single shard matching opts, or returns ErrCrossShard.
+// Unsharded Stores run fn directly.
+func (s *Store[T]) onShard(ctx context.Context, opts *where.Options, fn func(ctx context.Context) error) error {
+	if s.sharding == nil {
+		return fn(ctx)
+	}
+	tables, err := s.shards(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(tables) != 1 {
+		return fmt.Errorf("%w: %d shards", ErrCrossShard, len(tables))
+	}
+	return fn(withShard[T](ctx, tables[0]))
+}
+
+// getShards implements Get across the shards matching opts: the first object of each shard
+// is read and the first of them in the order of opts is returned.
+func (s *Store[T]) getShards(ctx context.Context, opts *where.Options, obj *T) error {
+	if s.sharding == nil {
+		return s.get(ctx, opts, obj)
+	}
+	tables, err := s.shards(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 1 {
+		return s.get(withShard[T](ctx, tables[0]), opts, obj)
+	}
+
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	var columns []orderColumn
+	if len(opts.Sorts) > 0 {
+		if columns, err = s.orderColumns(sch, opts.Sorts); err != nil {
+			return err
+		}
+	}
+	// Like First, each shard breaks ties by primary key in ascending order.
+	if sch.PrioritizedPrimaryField != nil {
+		columns = append(columns, orderColumn{Name: sch.PrioritizedPrimaryField.DBName})
+	}
+
+	// The shards read the sort keys even when opts selects other columns, to merge by them.
+	shardOpts := selectKeyset(opts, columns)
+	var found []*T
+	for _, table := range tables {
+		var candidate T
+		err := s.get(withShard[T](ctx, table), shardOpts, &candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = append(found, &candidate)
+	}
+	if len(found) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := sortObjects(ctx, sch, columns, found); err != nil {
+		return err
+	}
+	*obj = *found[0]
+	return nil
+}
+
+// listShards implements List across the shards matching opts: each shard lists its first
+// Offset+Limit objects, which are merged in the order of opts before the page is cut out of
+// them, and counts are summed.
+func (s *Store[T]) listShards(ctx context.Context, opts *where.Options, count *int64, ret *[]*T) error {
+	if s.sharding == nil {
+		return s.list(ctx, opts, count, ret)
+	}
+	tables, err := s.shards(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 1 {
+		return s.list(withShard[T](ctx, tables[0]), opts, count, ret)
+	}
+
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	columns, err := s.orderColumns(sch, opts.Sorts)
+	if err != nil {
+		return err
+	}
+
+	shardOpts := *selectKeyset(opts, columns)
+	shardOpts.Offset = 0
+	if opts.Limit > 0 {
+		shardOpts.Limit = opts.Offset + opts.Limit
+	}
+	var items []*T
+	for _, table := range tables {
+		var shardCount int64
+		var shardItems []*T
+		if err := s.list(withShard[T](ctx, table), &shardOpts, &shardCount, &shardItems); err != nil {
+			return err
+		}
+		*count += shardCount
+		items = append(items, shardItems...)
+	}
+	if err := sortObjects(ctx, sch, columns, items); err != nil {
+		return err
+	}
+
+	if opts.Offset > 0 {
+		items = items[min(opts.Offset, len(items)):]
+	}
+	if opts.Limit > 0 {
+		items = items[:min(opts.Limit, len(items))]
+	}
+	*ret = items
+	return nil
+}
+
+// sortObjects sorts objs stably by the values of columns.
+func sortObjects[T any](ctx context.Context, sch *schema.Schema, columns []orderColumn, objs []*T) error {
+	keys := make(map[*T][]any, len(objs))
+	for _, obj := range objs {
+		values, err := keysetValues(ctx, sch, columns, obj)
+		if err != nil {
+			return err
+		}
+		keys[obj] = values
+	}
+	slices.SortStableFunc(objs, func(a, b *T) int {
+		for i, col := range columns {
+			c := compareValues(keys[a][i], keys[b][i])
+			if col.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+// compareValues compares two column values of the same type, ordering nil first.
+func compareValues(a, b any) int {
+	a, b = indirectValue(a), indirectValue(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case x:
+				return 1
+			}
+			return -1
+		}
+	}
+
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case ra.CanInt() && rb.CanInt():
+		return cmp.Compare(ra.Int(), rb.Int())
+	case ra.CanUint() && rb.CanUint():
+		return cmp.Compare(ra.Uint(), rb.Uint())
+	case ra.CanFloat() && rb.CanFloat():
+		return cmp.Compare(ra.Float(), rb.Float())
+	case ra.Kind() == reflect.String && rb.Kind() == reflect.String:
+		return cmp.Compare(ra.String(), rb.String())
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// indirectValue dereferences pointers and resolves driver.Valuer values, such as sql.NullInt64,
+// returning nil for nil pointers and NULL values.
+func indirectValue(value any) any {
+	for value != nil {
+		if valuer, ok := value.(driver.Valuer); ok {
+			if rv := reflect.ValueOf(valuer); rv.Kind() == reflect.Pointer && rv.IsNil() {
+				return nil
+			}
+			v, err := valuer.Value()
+			if err != nil {
+				return value
+			}
+			value = v
+			continue
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Pointer {
+			return value
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		value = rv.Elem().Interface()
+	}
+	return nil
+}
+
+// shardingConditions collects the conditions of opts on the sharding key field: the values it
+// must equal, if any, and otherwise whether it is constrained by the range r.
+func shardingConditions(opts *where.Options, field *schema.Field) (values []any, r ShardRange, found bool) {
+	isKey := func(column any) bool {
+		var name string
+		switch c := column.(type) {
+		case string:
+			name = c
+		case clause.Column:
+			name = c.Name
+		default:
+			return false
+		}
+		return name == field.DBName || name == field.Name
+	}
+	// in narrows the values the key may equal to those also in more.
+	in := func(more []any) {
+		if values == nil {
+			values = slices.Clone(more)
+			return
+		}
+		values = slices.DeleteFunc(values, func(v any) bool {
+			return !slices.ContainsFunc(more, func(m any) bool { return compareValues(v, m) == 0 })
+		})
+	}
+
+	for key, value := range opts.Filters {
+		if !isKey(key) {
+			continue
+		}
+		rv := reflect.ValueOf(value)
+		if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+			more := make([]any, rv.Len())
+			for i := range more {
+				more[i] = rv.Index(i).Interface()
+			}
+			in(more)
+		} else {
+			in([]any{value})
+		}
+	}
+
+	var walk func(exprs []clause.Expression)
+	walk = func(exprs []clause.Expression) {
+		for _, expr := range exprs {
+			switch e := expr.(type) {
+			case clause.Eq:
+				if isKey(e.Column) {
+					in([]any{e.Value})
+				}
+			case clause.IN:
+				if isKey(e.Column) {
+					in(e.Values)
+				}
+			case clause.Gt:
+				if isKey(e.Column) {
+					r.Min, found = e.Value, true
+				}
+			case clause.Gte:
+				if isKey(e.Column) {
+					r.Min, found = e.Value, true
+				}
+			case clause.Lt:
+				if isKey(e.Column) {
+					r.Max, r.MaxExclusive, found = e.Value, true, true
+				}
+			case clause.Lte:
+				if isKey(e.Column) {
+					r.Max, r.MaxExclusive, found = e.Value, false, true
+				}
+			case clause.AndConditions:
+				walk(e.Exprs)
+			case clause.Where:
+				walk(e.Exprs)
+			}
+		}
+	}
+	walk(opts.Clauses)
+
+	if values != nil {
+		return values, ShardRange{}, true
+	}
+	return nil, r, found
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+type testOrder struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    int
+	Amount    int
+	CreatedAt time.Time
+}
+
+// dbProviderFunc adapts a function to a DBProvider.
+type dbProviderFunc func(ctx context.Context) *gorm.DB
+
+func (f dbProviderFunc) DB(ctx context.Context, _ ...where.Where) *gorm.DB {
+	return f(ctx)
+}
+
+func TestShardingStrategies(t *testing.T) {
+	hash := HashSharding{Shards: 4}
+	for value, want := range map[any]string{6: "orders_2", -1: "orders_3", uint8(5): "orders_1"} {
+		table, err := hash.Table("orders", value)
+		require.NoError(t, err)
+		assert.Equal(t, want, table)
+	}
+	a, err := hash.Table("orders", "alice")
+	require.NoError(t, err)
+	b, err := hash.Table("orders", []byte("alice"))
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+	assert.Equal(t, []string{"orders_0", "orders_1", "orders_2", "orders_3"}, hash.AllTables("orders"))
+	_, err = hash.Tables("orders", ShardRange{Min: 1, Max: 2})
+	assert.ErrorIs(t, err, ErrMissingShardingKey)
+
+	month := MonthSharding{Location: time.UTC}
+	table, err := month.Table("logs", time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "logs_202603", table)
+	tables, err := month.Tables("logs", ShardRange{
+		Min:          time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC),
+		Max:          time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		MaxExclusive: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logs_202512", "logs_202601"}, tables)
+	_, err = month.Tables("logs", ShardRange{Min: time.Now()})
+	assert.ErrorIs(t, err, ErrMissingShardingKey)
+}
+
+func TestStoreSharding(t *testing.T) {
+	ctx := context.Background()
+	hash := HashSharding{Shards: 2}
+	db := newTestDB(t)
+	for _, table := range hash.AllTables("test_orders") {
+		require.NoError(t, db.Table(table).AutoMigrate(&testOrder{}))
+	}
+	s := NewStore[testOrder](NewReadWriteProvider(db, nil), nil,
+		WithTenantExemption[testOrder](),
+		WithSharding[testOrder](Sharding{Column: "user_id", Strategy: hash, MaxFanOut: 2}),
+	)
+
+	orders := []*testOrder{
+		{UserID: 1, Amount: 10},
+		{UserID: 2, Amount: 40},
+		{UserID: 1, Amount: 30},
+		{UserID: 2, Amount: 20},
+	}
+	require.NoError(t, s.CreateBatch(ctx, orders, 10))
+	assert.False(t, orders[0].CreatedAt.IsZero())
+	var rows int64
+	require.NoError(t, db.Table("test_orders_1").Count(&rows).Error)
+	assert.Equal(t, int64(2), rows)
+
+	got, err := s.Get(ctx, where.F("user_id", 2).S("amount"))
+	require.NoError(t, err)
+	assert.Equal(t, 20, got.Amount)
+
+	both := where.F("user_id", []int{1, 2})
+	count, list, err := s.List(ctx, where.NewWhere().F("user_id", []int{1, 2}).S("-amount").P(1, 3))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	amounts := make([]int, 0, len(list))
+	for _, o := range list {
+		amounts = append(amounts, o.Amount)
+	}
+	assert.Equal(t, []int{40, 30, 20}, amounts)
+
+	got, err = s.Get(ctx, where.NewWhere().C(clause.IN{Column: "user_id", Values: []any{1, 2}}).S("amount"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, got.Amount)
+
+	n, err := s.Count(ctx, both)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	got.Amount = 15
+	require.NoError(t, s.Update(ctx, got))
+	require.NoError(t, s.UpdateColumns(ctx, where.F("user_id", []int{1, 2}, "amount", 40), map[string]any{"amount": 45}))
+	require.NoError(t, s.Delete(ctx, where.F("user_id", 2, "amount", 20)))
+	count, list, err = s.List(ctx, where.NewWhere().F("user_id", []int{1, 2}).S("amount"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	amounts = amounts[:0]
+	for _, o := range list {
+		amounts = append(amounts, o.Amount)
+	}
+	assert.Equal(t, []int{15, 30, 45}, amounts)
+
+	// Selected columns are qualified by the shard tables.
+	_, list, err = s.List(ctx, where.F("user_id", []int{1, 2}).S("amount").Select("amount"))
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, 15, list[0].Amount)
+	assert.Zero(t, list[0].UserID)
+	got, err = s.Get(ctx, where.F("user_id", 1).S("-amount").Select("amount"))
+	require.NoError(t, err)
+	assert.Equal(t, 30, got.Amount)
+	_, list, err = s.List(ctx, where.F("user_id", []int{1, 2}).S("-amount").Select("user_id"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 1}, []int{list[0].UserID, list[1].UserID, list[2].UserID})
+
+	var total int64
+	require.NoError(t, s.Pluck(ctx, where.F("user_id", 1).S("amount"), "amount", &[]int{}))
+	assert.ErrorIs(t, s.Aggregate(ctx, both, &total, Sum("amount", "total")), ErrCrossShard)
+
+	_, _, err = s.List(ctx, where.F("amount", 30))
+	assert.ErrorIs(t, err, ErrMissingShardingKey)
+	// SQL and filter DSL conditions on the sharding key do not select shards.
+	_, _, err = s.List(ctx, where.NewWhere().Q("user_id = ?", 1))
+	assert.ErrorIs(t, err, ErrMissingShardingKey)
+	dsl, err := where.FromFilterJSON(`{"user_id__in":"[1,2]"}`, "")
+	require.NoError(t, err)
+	_, _, err = s.List(ctx, dsl)
+	assert.ErrorIs(t, err, ErrMissingShardingKey)
+	n, err = s.Count(ctx, where.NewWhere().F("user_id", 1).Q("amount > ?", 20))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.ErrorIs(t, s.Create(ctx, &testOrder{Amount: 1}), ErrMissingShardingKey)
+	_, err = s.Count(ctx, where.F("user_id", []int{1, 2, 3, 4, 5, 6}))
+	assert.NoError(t, err, "values of the same shards do not add to the fan-out")
+
+	t.Run("shared db", func(t *testing.T) {
+		// A provider handing out the same instance is not poisoned by an unrouted statement.
+		shared := NewStore[testOrder](dbProviderFunc(func(context.Context) *gorm.DB { return db }), nil,
+			WithTenantExemption[testOrder](),
+			WithSharding[testOrder](Sharding{Column: "user_id", Strategy: hash}),
+		)
+		_, err := shared.Count(ctx, where.F("amount", 30))
+		assert.ErrorIs(t, err, ErrMissingShardingKey)
+		require.NoError(t, db.Error)
+		_, err = shared.Count(ctx, where.F("user_id", 1))
+		assert.NoError(t, err)
+	})
+
+	wide := NewStore[testOrder](NewReadWriteProvider(db, nil), nil,
+		WithTenantExemption[testOrder](),
+		WithSharding[testOrder](Sharding{Column: "user_id", Strategy: HashSharding{Shards: 3}, MaxFanOut: 2}),
+	)
+	_, err = wide.Count(ctx, where.F("user_id", []int{1, 2, 3}))
+	assert.ErrorIs(t, err, ErrTooManyShards)
+}
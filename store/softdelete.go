@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrSoftDeleteDisabled is returned by Restore when the Store has no soft delete configuration.
+var ErrSoftDeleteDisabled = errors.New("soft delete is not enabled")
+
+// defaultDeletedAtColumn is the column used when SoftDelete.DeletedAt is empty,
+// matching the entx DeletedAt mixin.
+const defaultDeletedAtColumn = "deleted_at"
+
+// SoftDelete describes the columns used to soft delete a model.
+type SoftDelete struct {
+	// DeletedAt is the nullable timestamp column set on deletion. Defaults to "deleted_at".
+	DeletedAt string
+	// DeletedBy is the optional column recording who deleted the row, e.g. "deleted_by".
+	DeletedBy string
+	// DeletedByFunc returns the value stored in DeletedBy, typically the operator ID carried by ctx.
+	DeletedByFunc func(ctx context.Context) any
+}
+
+// WithSoftDelete returns an Option that turns Delete into an update of the DeletedAt
+// (and DeletedBy) columns and hides soft-deleted rows from every other operation,
+// unless the where options include deleted rows.
+func WithSoftDelete[T any](sd SoftDelete) Option[T] {
+	return func(s *Store[T]) {
+		if sd.DeletedAt == "" {
+			sd.DeletedAt = defaultDeletedAtColumn
+		}
+		s.softDelete = &sd
+	}
+}
+
+// softDeleteScope hides soft-deleted rows from db unless one of wheres includes them.
+func (s *Store[T]) softDeleteScope(db *gorm.DB, wheres ...where.Where) *gorm.DB {
+	if s.softDelete == nil || includesDeleted(wheres...) {
+		return db
+	}
+	return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: s.softDelete.DeletedAt}, Value: nil})
+}
+
+// includesDeleted reports whether any of wheres asks for soft-deleted rows.
+func includesDeleted(wheres ...where.Where) bool {
+	for _, whr := range wheres {
+		if opts, ok := whr.(*where.Options); ok && opts != nil && opts.IncludeDeleted {
+			return true
+		}
+	}
+	return false
+}
+
+// withDeleted returns a copy of opts that also matches soft-deleted rows.
+func withDeleted(opts *where.Options) *where.Options {
+	whr := *opts
+	whr.IncludeDeleted = true
+	return &whr
+}
+
+// softDeleteValues returns the column values written when soft deleting (deleted) or restoring rows.
+func (s *Store[T]) softDeleteValues(ctx context.Context, deleted bool) map[string]any {
+	values := map[string]any{s.softDelete.DeletedAt: nil}
+	if deleted {
+		values[s.softDelete.DeletedAt] = time.Now()
+	}
+	if s.softDelete.DeletedBy != "" {
+		values[s.softDelete.DeletedBy] = nil
+		if deleted && s.softDelete.DeletedByFunc != nil {
+			values[s.softDelete.DeletedBy] = s.softDelete.DeletedByFunc(ctx)
+		}
+	}
+	return values
+}
+
+// HardDelete permanently removes the objects matching the provided where options,
+// including soft-deleted ones. Without any condition in opts, it returns gorm.ErrMissingWhereClause.
+func (s *Store[T]) HardDelete(ctx context.Context, opts *where.Options) error {
+	err := s.onShards(ctx, opts, func(ctx context.Context) error {
+		return s.hardDelete(ctx, opts)
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Error(ctx, err, "Failed to hard delete object from database", "conditions", opts)
+		return err
+	}
+	return nil
+}
+
+// hardDelete implements HardDelete.
+func (s *Store[T]) hardDelete(ctx context.Context, opts *where.Options) error {
+	if err := requireCondition(opts); err != nil {
+		return err
+	}
+	opts = withDeleted(opts)
+	load := func(ctx context.Context) ([]*T, error) {
+		return s.find(ctx, opts)
+	}
+	return s.observe(ctx, &Change[T]{Op: OpDelete, Conditions: opts}, load, false, func(ctx context.Context) error {
+		return s.db(ctx, opts).Unscoped().Delete(new(T)).Error
+	})
+}
+
+// Restore clears the soft delete marks of the objects matching the provided where options.
+// Without any condition in opts, it returns gorm.ErrMissingWhereClause.
+func (s *Store[T]) Restore(ctx context.Context, opts *where.Options) error {
+	err := s.onShards(ctx, opts, func(ctx context.Context) error {
+		return s.restore(ctx, opts)
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to restore object in database", "conditions", opts)
+		return err
+	}
+	return nil
+}
+
+// restore implements Restore.
+func (s *Store[T]) restore(ctx context.Context, opts *where.Options) error {
+	if s.softDelete == nil {
+		return ErrSoftDeleteDisabled
+	}
+	if err := requireCondition(opts); err != nil {
+		return err
+	}
+
+	deleted := clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: s.softDelete.DeletedAt}, Value: nil}
+	opts = withDeleted(opts)
+	load := func(ctx context.Context) ([]*T, error) {
+		var objs []*T
+		return objs, s.db(ctx, opts).Where(deleted).Offset(-1).Limit(-1).Find(&objs).Error
+	}
+	return s.observe(ctx, &Change[T]{Op: OpUpdate, Conditions: opts}, load, true, func(ctx context.Context) error {
+		return s.db(ctx, opts).Model(new(T)).Where(deleted).Updates(s.softDeleteValues(ctx, false)).Error
+	})
+}
+
+// ListWithDeleted retrieves a list of objects like List, including soft-deleted ones.
+func (s *Store[T]) ListWithDeleted(ctx context.Context, opts *where.Options) (count int64, ret []*T, err error) {
+	return s.List(ctx, withDeleted(opts))
+}
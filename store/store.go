@@ -2,11 +2,17 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"reflect"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 
+	"github.com/moweilong/mo/errorsx"
 	"github.com/moweilong/mo/store/logger/empty"
+	"github.com/moweilong/mo/store/mixin"
 	"github.com/moweilong/mo/store/where"
 )
 
@@ -16,13 +22,42 @@ type DBProvider interface {
 	DB(ctx context.Context, wheres ...where.Where) *gorm.DB
 }
 
+// IStore defines the basic operations of a Store on objects of type T. Depend on it rather
+// than on *Store[T] to test code against the in-memory implementation of the store/memory
+// package instead of a database.
+type IStore[T any] interface {
+	// Create inserts a new object.
+	Create(ctx context.Context, obj *T) error
+	// Update modifies an existing object.
+	Update(ctx context.Context, obj *T) error
+	// Delete removes the objects matching the where options.
+	Delete(ctx context.Context, opts *where.Options) error
+	// Get retrieves the first object matching the where options, or gorm.ErrRecordNotFound.
+	Get(ctx context.Context, opts *where.Options) (*T, error)
+	// List retrieves the objects matching the where options and their count.
+	List(ctx context.Context, opts *where.Options) (count int64, ret []*T, err error)
+}
+
+var _ IStore[struct{}] = (*Store[struct{}])(nil)
+
 // Option defines a function type for configuring the Store.
 type Option[T any] func(*Store[T])
 
 // Store represents a generic data store with logging capabilities.
 type Store[T any] struct {
-	logger  Logger
-	storage DBProvider
+	logger     Logger
+	storage    DBProvider
+	sortable   map[string]struct{}
+	filterable map[string]struct{}
+
+	tenantExempt     bool
+	tenantExemptKeys []string
+
+	dataScope  *DataScopeColumns
+	softDelete *SoftDelete
+	audit      *Audit
+	cache      *queryCache
+	sharding   *Sharding
 }
 
 // WithLogger returns an Option function that sets the provided Logger to the Store for logging purposes.
@@ -32,50 +67,309 @@ func WithLogger[T any](logger Logger) Option[T] {
 	}
 }
 
-// NewStore creates a new instance of Store with the provided DBProvider.
-func NewStore[T any](storage DBProvider, logger Logger) *Store[T] {
+// NewStore creates a new instance of Store with the provided DBProvider and options.
+func NewStore[T any](storage DBProvider, logger Logger, opts ...Option[T]) *Store[T] {
 	if logger == nil {
 		logger = empty.NewLogger()
 	}
 
-	return &Store[T]{
+	s := &Store[T]{
 		logger:  logger,
 		storage: storage,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // db retrieves the database instance and applies the provided where conditions.
+// If ctx carries a transaction on the Store's DBProvider, it is used instead of the
+// DBProvider's instance.
+// The returned instance is always restricted to the tenants and the data scope carried by
+// ctx and, unless wheres include them, hides soft-deleted rows.
 func (s *Store[T]) db(ctx context.Context, wheres ...where.Where) *gorm.DB {
-	dbInstance := s.storage.DB(ctx)
+	dbInstance, ok := txFor(ctx, s.storage)
+	if ok {
+		dbInstance = dbInstance.WithContext(ctx)
+	} else {
+		dbInstance = s.storage.DB(ctx)
+	}
+	return s.scope(ctx, dbInstance, wheres...)
+}
+
+// readDB is like db, but used for queries: when the DBProvider implements ReadDBProvider
+// and ctx carries neither a transaction nor a primary marker, the query goes to a replica.
+func (s *Store[T]) readDB(ctx context.Context, wheres ...where.Where) *gorm.DB {
+	reader, ok := s.storage.(ReadDBProvider)
+	if !ok || PrimaryForced(ctx) {
+		return s.db(ctx, wheres...)
+	}
+	if _, inTx := txFor(ctx, s.storage); inTx {
+		return s.db(ctx, wheres...)
+	}
+	return s.scope(ctx, reader.ReadDB(ctx), wheres...)
+}
+
+// scope applies the where conditions, the tenant isolation, the data scope and the soft delete
+// filter to dbInstance, failing on filters of non-filterable columns. For sharded models, it
+// also selects the shard table carried by ctx.
+func (s *Store[T]) scope(ctx context.Context, dbInstance *gorm.DB, wheres ...where.Where) *gorm.DB {
+	if s.sharding != nil {
+		if table, ok := shardFromContext[T](ctx); ok {
+			dbInstance = dbInstance.Table(table)
+		} else {
+			dbInstance = dbInstance.Session(&gorm.Session{})
+			_ = dbInstance.AddError(ErrMissingShardingKey)
+		}
+	}
+	if err := s.checkFilters(dbInstance, wheres); err != nil {
+		dbInstance = dbInstance.Session(&gorm.Session{})
+		_ = dbInstance.AddError(err)
+	}
 	for _, whr := range wheres {
 		if whr != nil {
 			dbInstance = whr.Where(dbInstance)
 		}
 	}
-	return dbInstance
+	return s.dataScopeFilter(ctx, s.tenantScope(ctx, s.softDeleteScope(dbInstance, wheres...)))
+}
+
+// Tx executes fn inside a transaction on the Store's DBProvider.
+// See TxManager.Tx for the commit, rollback and nesting semantics.
+func (s *Store[T]) Tx(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	return transaction(ctx, s.storage, s.logger, fn, opts...)
 }
 
 // Create inserts a new object into the database.
 func (s *Store[T]) Create(ctx context.Context, obj *T) error {
-	if err := s.db(ctx).Create(obj).Error; err != nil {
+	err := s.onObjectShards(ctx, []*T{obj}, func(ctx context.Context, _ []*T) error {
+		return s.create(ctx, obj)
+	})
+	if err != nil {
 		s.logger.Error(ctx, err, "Failed to insert object into database", "object", obj)
 		return err
 	}
 	return nil
 }
 
+// CreateBatch inserts objs into the database in batches of batchSize rows.
+func (s *Store[T]) CreateBatch(ctx context.Context, objs []*T, batchSize int) error {
+	if len(objs) == 0 {
+		return nil
+	}
+	err := s.onObjectShards(ctx, objs, func(ctx context.Context, objs []*T) error {
+		return s.createBatch(ctx, objs, batchSize)
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to batch insert objects into database", "count", len(objs), "batchSize", batchSize)
+		return err
+	}
+	return nil
+}
+
+// Upsert inserts objs, updating the existing rows that conflict with them.
+// conflictColumns identifies the conflicting rows; when empty the primary key is used.
+// updateColumns lists the columns to overwrite on conflict; when empty all columns are updated.
+// The dialect-specific clause (ON CONFLICT or ON DUPLICATE KEY UPDATE) is built by the GORM driver.
+// On MySQL, it fails with ErrUnscopedUpsert for models under tenant isolation or a data scope.
+func (s *Store[T]) Upsert(ctx context.Context, objs []*T, conflictColumns []string, updateColumns ...string) error {
+	if len(objs) == 0 {
+		return nil
+	}
+
+	onConflict := clause.OnConflict{}
+	for _, col := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: col})
+	}
+	if len(updateColumns) == 0 {
+		onConflict.UpdateAll = true
+	} else {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	}
+
+	err := s.onObjectShards(ctx, objs, func(ctx context.Context, objs []*T) error {
+		return s.upsert(ctx, objs, onConflict)
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to upsert objects into database", "count", len(objs), "conflictColumns", conflictColumns)
+		return err
+	}
+	return nil
+}
+
 // Update modifies an existing object in the database.
+// For models embedding mixin.Version, it returns errorsx.ErrVersionConflict when the object
+// was modified concurrently.
 func (s *Store[T]) Update(ctx context.Context, obj *T) error {
-	if err := s.db(ctx).Save(obj).Error; err != nil {
+	err := s.onObjectShards(ctx, []*T{obj}, func(ctx context.Context, _ []*T) error {
+		return s.update(ctx, obj)
+	})
+	if err != nil {
 		s.logger.Error(ctx, err, "Failed to update object in database", "object", obj)
 		return err
 	}
 	return nil
 }
 
+// create implements Create.
+func (s *Store[T]) create(ctx context.Context, obj *T) error {
+	if err := s.assignTenant(ctx, obj); err != nil {
+		return err
+	}
+	return s.observe(ctx, &Change[T]{Op: OpCreate, New: []*T{obj}}, nil, false, func(ctx context.Context) error {
+		return s.db(ctx).Create(obj).Error
+	})
+}
+
+// createBatch implements CreateBatch.
+func (s *Store[T]) createBatch(ctx context.Context, objs []*T, batchSize int) error {
+	if err := s.assignTenant(ctx, objs...); err != nil {
+		return err
+	}
+	return s.observe(ctx, &Change[T]{Op: OpCreate, New: objs}, nil, false, func(ctx context.Context) error {
+		return s.db(ctx).CreateInBatches(objs, batchSize).Error
+	})
+}
+
+// upsert implements Upsert. Under tenant isolation and data scopes, conflicting rows are only
+// updated when they belong to the same tenant and are within the data scope, using ON
+// CONFLICT ... WHERE. MySQL's ON DUPLICATE KEY UPDATE has no such condition, so upserts
+// updating rows fail with ErrUnscopedUpsert there instead. Hooks and the audit trail observe
+// it as a create, without the previous values of the updated rows.
+func (s *Store[T]) upsert(ctx context.Context, objs []*T, onConflict clause.OnConflict) error {
+	if err := s.assignTenant(ctx, objs...); err != nil {
+		return err
+	}
+
+	values, err := s.tenantValues(ctx)
+	if err != nil {
+		return err
+	}
+	onConflict.Where.Exprs = append(onConflict.Where.Exprs, tenantConditions(values)...)
+	cond, err := s.dataScopeCondition(ctx)
+	if err != nil {
+		return err
+	}
+	if cond != nil {
+		onConflict.Where.Exprs = append(onConflict.Where.Exprs, cond)
+	}
+	updates := onConflict.UpdateAll || len(onConflict.DoUpdates) > 0
+	if len(onConflict.Where.Exprs) > 0 && updates && s.db(ctx).Dialector.Name() == "mysql" {
+		return ErrUnscopedUpsert
+	}
+	return s.observe(ctx, &Change[T]{Op: OpCreate, New: objs}, nil, false, func(ctx context.Context) error {
+		return s.db(ctx).Clauses(onConflict).Create(objs).Error
+	})
+}
+
+// update implements Update.
+func (s *Store[T]) update(ctx context.Context, obj *T) error {
+	if err := s.assignTenant(ctx, obj); err != nil {
+		return err
+	}
+
+	load := func(ctx context.Context) ([]*T, error) {
+		return s.findByPrimaryKeys(ctx, []*T{obj})
+	}
+	return s.observe(ctx, &Change[T]{Op: OpUpdate, New: []*T{obj}}, load, false, func(ctx context.Context) error {
+		return s.save(ctx, obj)
+	})
+}
+
+// save writes obj. Under tenant isolation, data scopes or soft delete, the row is updated in
+// place rather than saved, because Save falls back to an upsert that could overwrite a row
+// out of scope or resurrect a soft-deleted one. Models implementing mixin.Versioned are
+// updated only if their version is unchanged in the database, and get their version
+// incremented.
+func (s *Store[T]) save(ctx context.Context, obj *T) error {
+	values, err := s.tenantValues(ctx)
+	if err != nil {
+		return err
+	}
+	versioned, isVersioned := any(obj).(mixin.Versioned)
+	if len(values) == 0 && s.dataScope == nil && s.softDelete == nil && !isVersioned {
+		return s.db(ctx).Save(obj).Error
+	}
+
+	// Without a primary key, the update below would match every row in scope.
+	if err := s.requirePrimaryKey(ctx, obj); err != nil {
+		return err
+	}
+	if !isVersioned {
+		return s.db(ctx).Model(obj).Select("*").Updates(obj).Error
+	}
+
+	version := versioned.GetVersion()
+	versioned.SetVersion(version + 1)
+	result := s.db(ctx).
+		Model(obj).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: mixin.VersionColumn}, Value: version}).
+		Select("*").
+		Updates(obj)
+	if result.Error != nil {
+		versioned.SetVersion(version)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		versioned.SetVersion(version)
+		return errorsx.ErrVersionConflict
+	}
+	return nil
+}
+
+// requirePrimaryKey returns gorm.ErrPrimaryKeyRequired if the primary key of obj is not set.
+func (s *Store[T]) requirePrimaryKey(ctx context.Context, obj *T) error {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	if len(sch.PrimaryFields) == 0 {
+		return gorm.ErrPrimaryKeyRequired
+	}
+	for _, field := range sch.PrimaryFields {
+		if _, zero := field.ValueOf(ctx, reflect.ValueOf(obj)); zero {
+			return gorm.ErrPrimaryKeyRequired
+		}
+	}
+	return nil
+}
+
+// UpdateColumns updates the given columns of all objects matching the provided where options.
+// Without any condition in opts, it returns gorm.ErrMissingWhereClause rather than updating
+// every row in scope.
+func (s *Store[T]) UpdateColumns(ctx context.Context, opts *where.Options, values map[string]any) error {
+	err := s.onShards(ctx, opts, func(ctx context.Context) error {
+		return s.updateColumns(ctx, opts, values)
+	})
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to update columns in database", "conditions", opts, "values", values)
+		return err
+	}
+	return nil
+}
+
+// updateColumns implements UpdateColumns.
+func (s *Store[T]) updateColumns(ctx context.Context, opts *where.Options, values map[string]any) error {
+	if err := requireCondition(opts); err != nil {
+		return err
+	}
+	load := func(ctx context.Context) ([]*T, error) {
+		return s.find(ctx, opts)
+	}
+	return s.observe(ctx, &Change[T]{Op: OpUpdate, Conditions: opts}, load, true, func(ctx context.Context) error {
+		return s.db(ctx, opts).Model(new(T)).Updates(values).Error
+	})
+}
+
 // Delete removes an object from the database based on the provided where options.
+// With soft delete enabled, the object is marked as deleted instead; see HardDelete.
+// Without any condition in opts, it returns gorm.ErrMissingWhereClause rather than deleting
+// every row.
 func (s *Store[T]) Delete(ctx context.Context, opts *where.Options) error {
-	err := s.db(ctx, opts).Delete(new(T)).Error
+	err := s.onShards(ctx, opts, func(ctx context.Context) error {
+		return s.delete(ctx, opts)
+	})
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		s.logger.Error(ctx, err, "Failed to delete object from database", "conditions", opts)
 		return err
@@ -83,21 +377,121 @@ func (s *Store[T]) Delete(ctx context.Context, opts *where.Options) error {
 	return nil
 }
 
+// delete implements Delete.
+func (s *Store[T]) delete(ctx context.Context, opts *where.Options) error {
+	if err := requireCondition(opts); err != nil {
+		return err
+	}
+	load := func(ctx context.Context) ([]*T, error) {
+		return s.find(ctx, opts)
+	}
+	return s.observe(ctx, &Change[T]{Op: OpDelete, Conditions: opts}, load, false, func(ctx context.Context) error {
+		if s.softDelete != nil {
+			return s.db(ctx, opts).Model(new(T)).Updates(s.softDeleteValues(ctx, true)).Error
+		}
+		return s.db(ctx, opts).Delete(new(T)).Error
+	})
+}
+
+// requireCondition returns gorm.ErrMissingWhereClause when opts carries no condition of the
+// caller. The soft delete, tenant and data scope conditions added by the Store would otherwise
+// let GORM run the statement, turning a forgotten condition into a write to every row in scope.
+func requireCondition(opts *where.Options) error {
+	if opts == nil || (len(opts.Filters) == 0 && len(opts.Clauses) == 0 && len(opts.Queries) == 0) {
+		return gorm.ErrMissingWhereClause
+	}
+	return nil
+}
+
+// count counts the objects matching opts, ignoring its pagination.
+func (s *Store[T]) count(ctx context.Context, sch *schema.Schema, opts *where.Options, count *int64) error {
+	db, err := s.withJoins(sch, s.readDB(ctx, opts), opts)
+	if err != nil {
+		return err
+	}
+	return db.Model(new(T)).Offset(-1).Limit(-1).Count(count).Error
+}
+
 // Get retrieves a single object from the database based on the provided where options.
+// When opts carries sort specifications, the first object in that order is returned.
 func (s *Store[T]) Get(ctx context.Context, opts *where.Options) (*T, error) {
 	var obj T
-	if err := s.db(ctx, opts).First(&obj).Error; err != nil {
+	if err := s.getShards(ctx, opts, &obj); err != nil {
 		s.logger.Error(ctx, err, "Failed to retrieve object from database", "conditions", opts)
 		return nil, err
 	}
 	return &obj, nil
 }
 
+// get implements Get.
+func (s *Store[T]) get(ctx context.Context, opts *where.Options, obj *T) error {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := s.withRelations(sch, s.readDB(ctx, opts), opts)
+	if err != nil {
+		return err
+	}
+	if len(opts.Sorts) > 0 {
+		columns, err := s.orderColumns(sch, opts.Sorts)
+		if err != nil {
+			return err
+		}
+		db = db.Clauses(orderBy(columns, false))
+	}
+
+	stmt := func() *gorm.DB { return dryRun(db).First(new(T)) }
+	return s.readThrough(ctx, "get", opts, stmt, obj, func() error {
+		return db.First(obj).Error
+	})
+}
+
 // List retrieves a list of objects from the database based on the provided where options.
+// Objects are ordered by the sort specifications of opts, or by primary key in descending
+// order when there are none. count is left as zero when counting is disabled in opts.
 func (s *Store[T]) List(ctx context.Context, opts *where.Options) (count int64, ret []*T, err error) {
-	err = s.db(ctx, opts).Order("id desc").Find(&ret).Offset(-1).Limit(-1).Count(&count).Error
+	err = s.listShards(ctx, opts, &count, &ret)
 	if err != nil {
 		s.logger.Error(ctx, err, "Failed to list objects from database", "conditions", opts)
 	}
 	return
 }
+
+// list implements List.
+func (s *Store[T]) list(ctx context.Context, opts *where.Options, count *int64, ret *[]*T) error {
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	columns, err := s.orderColumns(sch, opts.Sorts)
+	if err != nil {
+		return err
+	}
+
+	db, err := s.withRelations(sch, s.readDB(ctx, opts), opts)
+	if err != nil {
+		return err
+	}
+	if len(columns) > 0 {
+		db = db.Clauses(orderBy(columns, false))
+	}
+
+	kind := "list"
+	if opts.SkipCount {
+		kind = "list-items"
+	}
+	var result listResult[T]
+	stmt := func() *gorm.DB { return dryRun(db).Find(&[]*T{}) }
+	err = s.readThrough(ctx, kind, opts, stmt, &result, func() error {
+		if err := db.Find(&result.Items).Error; err != nil {
+			return err
+		}
+		if opts.SkipCount {
+			return nil
+		}
+		return s.count(ctx, sch, opts, &result.Count)
+	})
+	*count, *ret = result.Count, result.Items
+	return err
+}
@@ -0,0 +1,303 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/gormx"
+	"github.com/moweilong/mo/store/where"
+)
+
+type orgCtxKey struct{}
+
+// withOrg returns a copy of ctx carrying the org_id tenant.
+func withOrg(ctx context.Context, org string) context.Context {
+	return context.WithValue(ctx, orgCtxKey{}, org)
+}
+
+func TestMain(m *testing.M) {
+	// Tenant dimensions are global, so the one used by the tenant tests is registered for the
+	// whole package; the models of the other tests are exempted from it.
+	where.RegisterTenant("org_id", func(ctx context.Context) string {
+		org, _ := ctx.Value(orgCtxKey{}).(string)
+		return org
+	})
+	os.Exit(m.Run())
+}
+
+type testUser struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:64"`
+	Age  int
+}
+
+// newTestDB opens an in-memory SQLite database with the given models migrated.
+func newTestDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+
+	db, err := gormx.NewSQLite(&gormx.SQLiteOptions{Logger: logger.Discard})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// newUserStore creates a Store of testUser over a fresh database, seeded with users aged 0 to n-1.
+func newUserStore(t *testing.T, n int, opts ...Option[testUser]) *Store[testUser] {
+	t.Helper()
+
+	db := newTestDB(t, &testUser{})
+	opts = append([]Option[testUser]{WithTenantExemption[testUser]()}, opts...)
+	s := NewStore[testUser](NewReadWriteProvider(db, nil), nil, opts...)
+
+	users := make([]*testUser, 0, n)
+	for i := range n {
+		users = append(users, &testUser{Name: string(rune('a' + i)), Age: i})
+	}
+	if n > 0 {
+		require.NoError(t, s.CreateBatch(context.Background(), users, 3))
+	}
+	return s
+}
+
+func TestStoreCRUD(t *testing.T) {
+	ctx := context.Background()
+	s := newUserStore(t, 0)
+
+	user := &testUser{Name: "alice", Age: 30}
+	require.NoError(t, s.Create(ctx, user))
+	require.NotZero(t, user.ID)
+
+	got, err := s.Get(ctx, where.F("name", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, 30, got.Age)
+
+	got.Age = 31
+	require.NoError(t, s.Update(ctx, got))
+	got, err = s.Get(ctx, where.F("id", user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, 31, got.Age)
+
+	require.NoError(t, s.UpdateColumns(ctx, where.F("id", user.ID), map[string]any{"name": "alicia"}))
+	got, err = s.Get(ctx, where.F("id", user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "alicia", got.Name)
+
+	require.NoError(t, s.Delete(ctx, where.F("id", user.ID)))
+	_, err = s.Get(ctx, where.F("id", user.ID))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestStoreList(t *testing.T) {
+	ctx := context.Background()
+	s := newUserStore(t, 7)
+
+	count, users, err := s.List(ctx, where.P(2, 3).S("-age"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), count)
+	assert.Equal(t, []int{3, 2, 1}, ages(users))
+
+	count, users, err = s.List(ctx, where.S("age").Q("age >= ?", 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, []int{5, 6}, ages(users))
+
+	_, _, err = s.List(ctx, where.S("unknown"))
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestStoreFilterJSON(t *testing.T) {
+	ctx := context.Background()
+	filter := func(and string) *where.Options {
+		t.Helper()
+		opts, err := where.FromFilterJSON(and, "")
+		require.NoError(t, err)
+		return opts
+	}
+
+	s := newUserStore(t, 7)
+	count, _, err := s.List(ctx, filter(`{"age__gte":"5"}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	_, _, err = s.List(ctx, filter(`{"password__startswith":"a"}`))
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	// With a whitelist, the other columns cannot be filtered on by any operation.
+	s = newUserStore(t, 7, WithFilterableColumns[testUser]("Name"))
+	count, _, err = s.List(ctx, filter(`{"name__in":"[\"a\",\"b\"]"}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	_, _, err = s.List(ctx, filter(`{"age__gte":"5"}`))
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	_, err = s.Count(ctx, filter(`{"age__gte":"5"}`))
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	assert.ErrorIs(t, s.Delete(ctx, filter(`{"age__gte":"5"}`)), ErrInvalidFilter)
+
+	// Conditions set by the application itself are not restricted.
+	count, _, err = s.List(ctx, where.F("age", 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = s.Count(ctx, where.NewWhere())
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), count)
+}
+
+func TestStoreListPage(t *testing.T) {
+	ctx := context.Background()
+	s := newUserStore(t, 7)
+
+	page, err := s.ListPage(ctx, where.L(3).S("-age"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), page.Total)
+	assert.Equal(t, []int{6, 5, 4}, ages(page.Items))
+	assert.Empty(t, page.PrevCursor)
+
+	page, err = s.ListPage(ctx, where.L(3).S("-age").After(page.NextCursor))
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 2, 1}, ages(page.Items))
+
+	last, err := s.ListPage(ctx, where.L(3).S("-age").After(page.NextCursor).NoCount())
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, ages(last.Items))
+	assert.Zero(t, last.Total)
+	assert.Empty(t, last.NextCursor)
+
+	page, err = s.ListPage(ctx, where.L(3).S("-age").Before(last.PrevCursor))
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 2, 1}, ages(page.Items))
+	assert.NotEmpty(t, page.NextCursor)
+	assert.NotEmpty(t, page.PrevCursor)
+
+	// A cursor only continues the ordering it was created for.
+	for _, sort := range []string{"age", "-name"} {
+		_, err = s.ListPage(ctx, where.L(3).S(sort).After(page.NextCursor))
+		assert.ErrorIs(t, err, where.ErrInvalidCursor, sort)
+	}
+}
+
+func TestStoreIterate(t *testing.T) {
+	ctx := context.Background()
+	s := newUserStore(t, 7)
+	// Ties on age are broken by primary key across batches.
+	require.NoError(t, s.Create(ctx, &testUser{Name: "h", Age: 2}))
+
+	collect := func(seq iter.Seq2[*testUser, error]) []int {
+		var ret []int
+		for user, err := range seq {
+			require.NoError(t, err)
+			ret = append(ret, user.Age)
+		}
+		return ret
+	}
+	assert.Equal(t, []int{0, 1, 2, 2, 3, 4, 5, 6}, collect(s.Iterate(ctx, where.L(3).S("age"))))
+	// Without sort specifications, objects are ordered by primary key in descending order.
+	assert.Equal(t, []int{2, 6, 5, 4, 3, 2, 1, 0}, collect(s.Iterate(ctx, where.L(3))))
+	assert.Equal(t, []int{6, 5}, collect(s.Iterate(ctx, where.L(2).S("-age").Q("age > ?", 4))))
+
+	page, err := s.ListPage(ctx, where.L(2).S("age"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 3, 4, 5, 6}, collect(s.Iterate(ctx, where.L(4).S("age").After(page.NextCursor))))
+
+	var seen int
+	for range s.Iterate(ctx, where.L(2)) {
+		if seen++; seen == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, seen)
+
+	iterErrs := func(ctx context.Context, opts *where.Options) []error {
+		var errs []error
+		for user, err := range s.Iterate(ctx, opts) {
+			assert.Nil(t, user)
+			errs = append(errs, err)
+		}
+		return errs
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	errs := iterErrs(canceled, where.NewWhere())
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+
+	errs = iterErrs(ctx, where.S("unknown"))
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrInvalidSort)
+}
+
+func TestStoreTx(t *testing.T) {
+	ctx := context.Background()
+	s := newUserStore(t, 0)
+
+	errInner := errors.New("inner")
+	err := s.Tx(ctx, func(ctx context.Context) error {
+		require.NoError(t, s.Create(ctx, &testUser{Name: "outer"}))
+		// The nested transaction is a savepoint, rolled back alone.
+		err := s.Tx(ctx, func(ctx context.Context) error {
+			require.NoError(t, s.Create(ctx, &testUser{Name: "inner"}))
+			return errInner
+		})
+		assert.ErrorIs(t, err, errInner)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = s.Tx(ctx, func(ctx context.Context) error {
+		require.NoError(t, s.Create(ctx, &testUser{Name: "rolled back"}))
+		return errInner
+	})
+	assert.ErrorIs(t, err, errInner)
+
+	_, users, err := s.List(ctx, where.NewWhere())
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "outer", users[0].Name)
+}
+
+func ages(users []*testUser) []int {
+	ret := make([]int, 0, len(users))
+	for _, user := range users {
+		ret = append(ret, user.Age)
+	}
+	return ret
+}
+
+func TestStoreDeleteRequiresCondition(t *testing.T) {
+	type testNote struct {
+		ID        uint `gorm:"primaryKey"`
+		Title     string
+		DeletedAt *time.Time
+	}
+	ctx := context.Background()
+	s := NewStore[testNote](NewReadWriteProvider(newTestDB(t, &testNote{}), nil), nil,
+		WithTenantExemption[testNote](), WithSoftDelete[testNote](SoftDelete{}))
+	require.NoError(t, s.CreateBatch(ctx, []*testNote{{Title: "a"}, {Title: "b"}}, 10))
+
+	assert.ErrorIs(t, s.Delete(ctx, where.NewWhere()), gorm.ErrMissingWhereClause)
+	assert.ErrorIs(t, s.HardDelete(ctx, where.NewWhere()), gorm.ErrMissingWhereClause)
+	assert.ErrorIs(t, s.Restore(ctx, where.NewWhere()), gorm.ErrMissingWhereClause)
+	n, err := s.Count(ctx, where.NewWhere())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	require.NoError(t, s.Delete(ctx, where.F("title", "a")))
+	n, err = s.Count(ctx, where.NewWhere())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	require.NoError(t, s.Restore(ctx, where.F("title", "a")))
+	n, err = s.Count(ctx, where.NewWhere())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
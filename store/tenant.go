@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// ErrUnscopedUpsert is returned by Upsert on MySQL when the model is isolated by tenant or
+// restricted by a data scope, because ON DUPLICATE KEY UPDATE would overwrite conflicting
+// rows of other tenants or out of the data scope.
+var ErrUnscopedUpsert = errors.New("upsert cannot be restricted to the tenant and data scope on MySQL")
+
+// WithTenantExemption returns an Option that exempts the model from the given tenant
+// dimensions, or from tenant isolation entirely when no key is given. Models without
+// a column for a registered tenant dimension must be exempted from it.
+func WithTenantExemption[T any](keys ...string) Option[T] {
+	return func(s *Store[T]) {
+		if len(keys) == 0 {
+			s.tenantExempt = true
+			return
+		}
+		s.tenantExemptKeys = append(s.tenantExemptKeys, keys...)
+	}
+}
+
+// tenantValues returns the tenant values that isolate the model for ctx.
+// It returns nothing when isolation does not apply and fails when a tenant value is missing.
+func (s *Store[T]) tenantValues(ctx context.Context) (map[string]string, error) {
+	if s.tenantExempt || where.TenantSkipped(ctx) {
+		return nil, nil
+	}
+	return where.TenantValues(ctx, s.tenantExemptKeys...)
+}
+
+// tenantScope restricts db to the rows of the tenants carried by ctx. If a tenant value is
+// missing, the error is added to db so that the statement fails instead of leaking rows.
+func (s *Store[T]) tenantScope(ctx context.Context, db *gorm.DB) *gorm.DB {
+	values, err := s.tenantValues(ctx)
+	if err != nil {
+		db = db.Session(&gorm.Session{})
+		_ = db.AddError(err)
+		return db
+	}
+
+	if conds := tenantConditions(values); len(conds) > 0 {
+		db = db.Where(clause.And(conds...))
+	}
+	return db
+}
+
+// tenantConditions builds the equality conditions for the tenant values, in a stable order.
+func tenantConditions(values map[string]string) []clause.Expression {
+	conds := make([]clause.Expression, 0, len(values))
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		conds = append(conds, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: key}, Value: values[key]})
+	}
+	return conds
+}
+
+// assignTenant sets the tenant columns of objs from ctx. Objects that already carry
+// a different tenant value are rejected.
+func (s *Store[T]) assignTenant(ctx context.Context, objs ...*T) error {
+	values, err := s.tenantValues(ctx)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+
+	sch, err := s.schema(ctx)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		field := sch.LookUpField(key)
+		if field == nil {
+			return fmt.Errorf("model %s has no tenant column %q", sch.Name, key)
+		}
+		for _, obj := range objs {
+			rv := reflect.ValueOf(obj)
+			current, zero := field.ValueOf(ctx, rv)
+			if zero {
+				if err := field.Set(ctx, rv, value); err != nil {
+					return err
+				}
+				continue
+			}
+			if fmt.Sprint(current) != value {
+				return fmt.Errorf("%w: %s", where.ErrTenantMismatch, key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+type testDoc struct {
+	ID    uint   `gorm:"primaryKey"`
+	OrgID string `gorm:"size:32"`
+	Title string `gorm:"size:64"`
+}
+
+func TestStoreTenantIsolation(t *testing.T) {
+	db := newTestDB(t, &testDoc{})
+	s := NewStore[testDoc](NewReadWriteProvider(db, nil), nil)
+
+	a, b := withOrg(context.Background(), "a"), withOrg(context.Background(), "b")
+	docA, docB := &testDoc{Title: "a1"}, &testDoc{Title: "b1"}
+	require.NoError(t, s.Create(a, docA))
+	require.NoError(t, s.Create(b, docB))
+	assert.Equal(t, "a", docA.OrgID)
+
+	t.Run("missing tenant", func(t *testing.T) {
+		assert.ErrorIs(t, s.Create(context.Background(), &testDoc{Title: "x"}), where.ErrMissingTenant)
+		_, _, err := s.List(context.Background(), where.NewWhere())
+		assert.ErrorIs(t, err, where.ErrMissingTenant)
+	})
+
+	t.Run("tenant mismatch", func(t *testing.T) {
+		assert.ErrorIs(t, s.Create(a, &testDoc{Title: "x", OrgID: "b"}), where.ErrTenantMismatch)
+	})
+
+	t.Run("reads are filtered", func(t *testing.T) {
+		count, docs, err := s.List(a, where.NewWhere())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		assert.Equal(t, "a1", docs[0].Title)
+
+		page, err := s.ListPage(a, where.L(10))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), page.Total)
+		assert.Len(t, page.Items, 1)
+
+		_, err = s.Get(a, where.F("id", docB.ID))
+		assert.Error(t, err)
+	})
+
+	t.Run("writes are filtered", func(t *testing.T) {
+		require.NoError(t, s.UpdateColumns(a, where.F("id", docB.ID), map[string]any{"title": "hacked"}))
+		require.NoError(t, s.Delete(a, where.F("id", docB.ID)))
+
+		doc, err := s.Get(b, where.F("id", docB.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "b1", doc.Title)
+
+		// The tenant condition alone does not count as a condition of the caller.
+		assert.ErrorIs(t, s.UpdateColumns(a, where.NewWhere(), map[string]any{"title": "wiped"}), gorm.ErrMissingWhereClause)
+		assert.ErrorIs(t, s.Delete(a, where.NewWhere()), gorm.ErrMissingWhereClause)
+		doc, err = s.Get(a, where.F("id", docA.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "a1", doc.Title)
+	})
+
+	t.Run("without tenant", func(t *testing.T) {
+		count, _, err := s.List(where.WithoutTenant(context.Background()), where.NewWhere())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+}
+
+func TestStoreTenantUpsertMySQL(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:3306)/db", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	ctx := withOrg(context.Background(), "a")
+
+	// ON DUPLICATE KEY UPDATE cannot be restricted to the rows of the tenant.
+	s := NewStore[testDoc](NewReadWriteProvider(db, nil), nil)
+	assert.ErrorIs(t, s.Upsert(ctx, []*testDoc{{ID: 1, Title: "x"}}, nil), ErrUnscopedUpsert)
+	assert.ErrorIs(t, s.Upsert(ctx, []*testDoc{{ID: 1, Title: "x"}}, nil, "title"), ErrUnscopedUpsert)
+
+	exempt := NewStore[testDoc](NewReadWriteProvider(db, nil), nil, WithTenantExemption[testDoc]())
+	assert.NoError(t, exempt.Upsert(ctx, []*testDoc{{ID: 1, Title: "x"}}, nil))
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+// databaseSetting is the gorm setting holding the name of the database a TenantProvider
+// returned, which scopes the query cache keys to it.
+const databaseSetting = "store:database"
+
+// TenantProviderOption configures a TenantProvider.
+type TenantProviderOption func(*TenantProvider)
+
+// WithDefaultDB sets the database of the tenants without a dedicated database, and of the
+// contexts without tenant. Without it, they fail with ErrUnknownDB and where.ErrMissingTenant.
+func WithDefaultDB(name string) TenantProviderOption {
+	return func(p *TenantProvider) {
+		p.defaultDB = name
+	}
+}
+
+// TenantProvider is a DBProvider routing each context to the database of its tenant, for
+// tenants with a dedicated database. The databases are held by a DBManager, and tenants
+// can be routed to databases while the provider is in use.
+type TenantProvider struct {
+	manager   *DBManager
+	tenantKey string
+	defaultDB string
+
+	mu     sync.RWMutex
+	routes map[string]string
+}
+
+var _ DBProvider = (*TenantProvider)(nil)
+
+// NewTenantProvider creates a TenantProvider routing contexts by the value of the tenant
+// dimension registered under tenantKey with where.RegisterTenant.
+func NewTenantProvider(manager *DBManager, tenantKey string, opts ...TenantProviderOption) *TenantProvider {
+	p := &TenantProvider{
+		manager:   manager,
+		tenantKey: tenantKey,
+		routes:    map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Route routes tenant to the database registered under name with the DBManager.
+func (p *TenantProvider) Route(tenant, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes[tenant] = name
+}
+
+// Unroute routes tenant back to the default database.
+func (p *TenantProvider) Unroute(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.routes, tenant)
+}
+
+// AddTenant registers the dedicated database of tenant with the DBManager, under the name
+// of the tenant, and routes tenant to it. It is opened on first use.
+func (p *TenantProvider) AddTenant(tenant string, opener DBOpener) error {
+	if err := p.manager.Add(tenant, opener); err != nil {
+		return err
+	}
+	p.Route(tenant, tenant)
+	return nil
+}
+
+// RemoveTenant routes tenant back to the default database, then unregisters and closes
+// the dedicated database registered by AddTenant.
+func (p *TenantProvider) RemoveTenant(tenant string) error {
+	p.Unroute(tenant)
+	return p.manager.Remove(tenant)
+}
+
+// DB returns the database of the tenant in ctx. When it cannot be provided, the returned
+// instance fails every statement with the reason.
+func (p *TenantProvider) DB(ctx context.Context, wheres ...where.Where) *gorm.DB {
+	name, err := p.route(ctx)
+	if err != nil {
+		return invalidDB(ctx, err)
+	}
+	db, err := p.manager.Get(name)
+	if err != nil {
+		return invalidDB(ctx, err)
+	}
+	return db.WithContext(ctx).Set(databaseSetting, name)
+}
+
+// route returns the name of the database of the tenant in ctx.
+func (p *TenantProvider) route(ctx context.Context) (string, error) {
+	tenant := where.TenantValue(ctx, p.tenantKey)
+	if tenant != "" {
+		p.mu.RLock()
+		name, ok := p.routes[tenant]
+		p.mu.RUnlock()
+		if ok {
+			return name, nil
+		}
+	}
+
+	switch {
+	case p.defaultDB != "":
+		return p.defaultDB, nil
+	case tenant == "":
+		return "", fmt.Errorf("%w: %s", where.ErrMissingTenant, p.tenantKey)
+	default:
+		return "", fmt.Errorf("%w: no database for tenant %s", ErrUnknownDB, tenant)
+	}
+}
+
+var (
+	invalidOnce sync.Once
+	invalid     *gorm.DB
+)
+
+// invalidDB returns a database instance failing every statement with err.
+func invalidDB(ctx context.Context, err error) *gorm.DB {
+	invalidOnce.Do(func() {
+		// invalidDialector opens no connection and registers no callbacks, so it cannot fail.
+		invalid, _ = gorm.Open(invalidDialector{}, &gorm.Config{Logger: logger.Discard})
+	})
+	db := invalid.WithContext(ctx)
+	_ = db.AddError(err)
+	return db
+}
+
+// invalidDialector is the dialector of invalidDB: without connection nor callbacks,
+// statements only return the error of the instance.
+type invalidDialector struct{}
+
+func (invalidDialector) Name() string                                        { return "invalid" }
+func (invalidDialector) Initialize(*gorm.DB) error                           { return nil }
+func (invalidDialector) Migrator(*gorm.DB) gorm.Migrator                     { return nil }
+func (invalidDialector) DataTypeOf(*schema.Field) string                     { return "" }
+func (invalidDialector) DefaultValueOf(*schema.Field) clause.Expression      { return clause.Expr{} }
+func (invalidDialector) BindVarTo(w clause.Writer, _ *gorm.Statement, _ any) { _ = w.WriteByte('?') }
+func (invalidDialector) QuoteTo(w clause.Writer, str string)                 { _, _ = w.WriteString(str) }
+func (invalidDialector) Explain(sql string, vars ...any) string              { return sql }
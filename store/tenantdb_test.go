@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/cache"
+	"github.com/moweilong/mo/store/where"
+)
+
+// testDBOpener returns a DBOpener of fresh in-memory databases, counting its calls.
+func testDBOpener(t *testing.T, opened *int) DBOpener {
+	return DBOpenerFunc(func() (*gorm.DB, error) {
+		*opened++
+		return newTestDB(t, &testUser{}), nil
+	})
+}
+
+func TestDBManager(t *testing.T) {
+	var opened int
+	m := NewDBManager(map[string]DBOpener{"shared": testDBOpener(t, &opened)})
+	defer func() { _ = m.Close() }()
+	assert.Zero(t, opened)
+
+	db1, err := m.Get("shared")
+	require.NoError(t, err)
+	db2, err := m.Get("shared")
+	require.NoError(t, err)
+	assert.Same(t, db1, db2)
+	assert.Equal(t, 1, opened)
+
+	_, err = m.Get("missing")
+	assert.ErrorIs(t, err, ErrUnknownDB)
+
+	failing := 0
+	require.NoError(t, m.Add("flaky", DBOpenerFunc(func() (*gorm.DB, error) {
+		if failing++; failing == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return newTestDB(t), nil
+	})))
+	_, err = m.Get("flaky")
+	assert.ErrorContains(t, err, "connection refused")
+	_, err = m.Get("flaky")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flaky", "shared"}, m.Names())
+
+	require.NoError(t, m.Remove("shared"))
+	sqlDB, err := db1.DB()
+	require.NoError(t, err)
+	assert.Error(t, sqlDB.Ping())
+	_, err = m.Get("shared")
+	assert.ErrorIs(t, err, ErrUnknownDB)
+	assert.ErrorIs(t, m.Remove("shared"), ErrUnknownDB)
+}
+
+func TestTenantProvider(t *testing.T) {
+	var opened int
+	m := NewDBManager(map[string]DBOpener{"shared": testDBOpener(t, &opened)})
+	defer func() { _ = m.Close() }()
+	p := NewTenantProvider(m, "org_id", WithDefaultDB("shared"))
+	s := NewStore[testUser](p, nil, WithTenantExemption[testUser](), WithCache[testUser](cache.NewLRU(100), time.Minute))
+
+	acme, other := withOrg(context.Background(), "acme"), withOrg(context.Background(), "other")
+	names := func(ctx context.Context) []string {
+		t.Helper()
+		_, users, err := s.List(ctx, where.NewWhere())
+		require.NoError(t, err)
+		ret := make([]string, 0, len(users))
+		for _, u := range users {
+			ret = append(ret, u.Name)
+		}
+		return ret
+	}
+
+	require.NoError(t, s.Create(other, &testUser{Name: "bob"}))
+	assert.Equal(t, []string{"bob"}, names(acme))
+
+	// The dedicated database is added at runtime and opened on first use.
+	require.NoError(t, p.AddTenant("acme", testDBOpener(t, &opened)))
+	assert.Equal(t, 1, opened)
+	require.NoError(t, s.Tx(acme, func(ctx context.Context) error {
+		return s.Create(ctx, &testUser{Name: "alice"})
+	}))
+	assert.Equal(t, 2, opened)
+	assert.Equal(t, []string{"alice"}, names(acme))
+	assert.Equal(t, []string{"bob"}, names(other))
+
+	require.NoError(t, p.RemoveTenant("acme"))
+	assert.Equal(t, []string{"bob"}, names(acme))
+
+	t.Run("no database", func(t *testing.T) {
+		strict := NewStore[testUser](NewTenantProvider(m, "org_id"), nil, WithTenantExemption[testUser]())
+		_, _, err := strict.List(acme, where.NewWhere())
+		assert.ErrorIs(t, err, ErrUnknownDB)
+		assert.ErrorIs(t, strict.Create(context.Background(), &testUser{}), where.ErrMissingTenant)
+		assert.ErrorIs(t, strict.Tx(acme, func(context.Context) error { return nil }), ErrUnknownDB)
+	})
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/logger/empty"
+)
+
+// txKey is the context key under which the active transaction on a DBProvider is stored,
+// so that Stores on other DBProviders do not join it. The innermost transaction, whatever
+// its DBProvider, is also stored under the zero txKey.
+type txKey struct {
+	provider any
+}
+
+// commitKey is the context key under which the callbacks to run once the
+// outermost transaction on a DBProvider commits are stored.
+type commitKey struct {
+	provider any
+}
+
+// sharedProvider identifies, in context keys, the DBProviders that cannot be compared.
+type sharedProvider struct{}
+
+// providerKey returns the value identifying storage in context keys. DBProviders that cannot
+// be compared, such as functions, share their transactions, as they cannot be told apart.
+func providerKey(storage DBProvider) any {
+	if storage != nil && !reflect.ValueOf(storage).Comparable() {
+		return sharedProvider{}
+	}
+	return storage
+}
+
+// commitCallbacks collects the callbacks to run after a transaction commits.
+type commitCallbacks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// TxManager runs functions inside a database transaction. The transaction is
+// propagated through the context, so every Store on the same DBProvider that
+// receives the context passed to the callback transparently joins it.
+type TxManager struct {
+	logger  Logger
+	storage DBProvider
+}
+
+// NewTxManager creates a new instance of TxManager with the provided DBProvider.
+func NewTxManager(storage DBProvider, logger Logger) *TxManager {
+	if logger == nil {
+		logger = empty.NewLogger()
+	}
+
+	return &TxManager{
+		logger:  logger,
+		storage: storage,
+	}
+}
+
+// Tx executes fn inside a transaction. The transaction is committed when fn
+// returns nil and rolled back when fn returns an error or panics. Calling Tx
+// with a context that already carries a transaction creates a savepoint
+// instead, so nested calls can be rolled back independently.
+func (m *TxManager) Tx(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	return transaction(ctx, m.storage, m.logger, fn, opts...)
+}
+
+// WithTx returns a copy of ctx that carries the given transaction on storage. Only the
+// Stores on storage join it.
+func WithTx(ctx context.Context, storage DBProvider, tx *gorm.DB) context.Context {
+	ctx = context.WithValue(ctx, txKey{provider: providerKey(storage)}, tx)
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the innermost transaction stored in ctx, if any. In hooks, it is
+// the transaction of the write.
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	return txFor(ctx, nil)
+}
+
+// txFor returns the transaction on storage stored in ctx, if any.
+func txFor(ctx context.Context, storage DBProvider) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{provider: providerKey(storage)}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// transaction begins a transaction (or a savepoint when one is already active)
+// and runs fn with a context carrying it.
+func transaction(
+	ctx context.Context,
+	storage DBProvider,
+	logger Logger,
+	fn func(ctx context.Context) error,
+	opts ...*sql.TxOptions,
+) error {
+	if err := runTx(ctx, storage, fn, opts...); err != nil {
+		logger.Error(ctx, err, "Failed to execute transaction")
+		return err
+	}
+	return nil
+}
+
+// runTx is transaction without logging, for writes that log their own errors.
+func runTx(ctx context.Context, storage DBProvider, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	db, ok := txFor(ctx, storage)
+	if ok {
+		return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(WithTx(ctx, storage, tx))
+		}, opts...)
+	}
+
+	db = storage.DB(ctx)
+	if db.Error != nil {
+		return db.Error
+	}
+
+	callbacks := &commitCallbacks{}
+	ctx = context.WithValue(ctx, commitKey{provider: providerKey(storage)}, callbacks)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(WithTx(ctx, storage, tx))
+	}, opts...)
+	if err == nil {
+		for _, cb := range callbacks.fns {
+			cb()
+		}
+	}
+	return err
+}
+
+// afterCommit runs fn once the transaction on storage carried by ctx commits, or
+// immediately when ctx carries no such transaction. Callbacks registered in a transaction
+// that is rolled back are discarded with it, except when only a savepoint is rolled back.
+func afterCommit(ctx context.Context, storage DBProvider, fn func()) {
+	callbacks, ok := ctx.Value(commitKey{provider: providerKey(storage)}).(*commitCallbacks)
+	if _, inTx := txFor(ctx, storage); !inTx || !ok {
+		fn()
+		return
+	}
+
+	callbacks.mu.Lock()
+	defer callbacks.mu.Unlock()
+	callbacks.fns = append(callbacks.fns, fn)
+}
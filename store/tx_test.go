@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/moweilong/mo/store/where"
+)
+
+func TestTxManager(t *testing.T) {
+	ctx := context.Background()
+	provider := NewReadWriteProvider(newTestDB(t, &testUser{}, &testDoc{}), nil)
+	m := NewTxManager(provider, nil)
+	users := NewStore[testUser](provider, nil, WithTenantExemption[testUser]())
+	docs := NewStore[testDoc](provider, nil, WithTenantExemption[testDoc]())
+	errAbort := errors.New("abort")
+
+	names := func() []string {
+		t.Helper()
+		_, list, err := users.List(ctx, where.S("id"))
+		require.NoError(t, err)
+		ret := make([]string, 0, len(list))
+		for _, u := range list {
+			ret = append(ret, u.Name)
+		}
+		return ret
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		err := m.Tx(ctx, func(ctx context.Context) error {
+			_, ok := TxFromContext(ctx)
+			assert.True(t, ok)
+			// Every Store given the context joins the transaction and sees its writes.
+			require.NoError(t, users.Create(ctx, &testUser{Name: "alice"}))
+			require.NoError(t, docs.Create(ctx, &testDoc{Title: "alice's doc"}))
+			n, err := users.Count(ctx, where.F("name", "alice"))
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, names())
+		n, err := docs.Count(ctx, where.NewWhere())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		err := m.Tx(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Create(ctx, &testUser{Name: "bob"}))
+			require.NoError(t, docs.Create(ctx, &testDoc{Title: "bob's doc"}))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		assert.Equal(t, []string{"alice"}, names())
+		n, err := docs.Count(ctx, where.NewWhere())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			_ = m.Tx(ctx, func(ctx context.Context) error {
+				require.NoError(t, users.Create(ctx, &testUser{Name: "carol"}))
+				panic("boom")
+			})
+		})
+		assert.Equal(t, []string{"alice"}, names())
+	})
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		err := m.Tx(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Create(ctx, &testUser{Name: "dave"}))
+			err := m.Tx(ctx, func(ctx context.Context) error {
+				require.NoError(t, users.Create(ctx, &testUser{Name: "erin"}))
+				return errAbort
+			})
+			assert.ErrorIs(t, err, errAbort)
+			// The outer transaction goes on without the writes of the rolled back savepoint.
+			n, err := users.Count(ctx, where.F("name", "erin"))
+			require.NoError(t, err)
+			assert.Zero(t, n)
+			return m.Tx(ctx, func(ctx context.Context) error {
+				return users.Create(ctx, &testUser{Name: "frank"})
+			})
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice", "dave", "frank"}, names())
+	})
+
+	t.Run("other provider", func(t *testing.T) {
+		other := NewStore[testDoc](NewReadWriteProvider(newTestDB(t, &testDoc{}), nil), nil, WithTenantExemption[testDoc]())
+		err := m.Tx(ctx, func(ctx context.Context) error {
+			// A Store on another DBProvider does not join the transaction.
+			require.NoError(t, other.Create(ctx, &testDoc{Title: "other"}))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		n, err := other.Count(ctx, where.NewWhere())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		n, err = docs.Count(ctx, where.F("title", "other"))
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("uncomparable provider", func(t *testing.T) {
+		db := newTestDB(t, &testDoc{})
+		funcDocs := NewStore[testDoc](dbProviderFunc(func(ctx context.Context) *gorm.DB { return db.WithContext(ctx) }), nil,
+			WithTenantExemption[testDoc]())
+		err := funcDocs.Tx(ctx, func(ctx context.Context) error {
+			require.NoError(t, funcDocs.Create(ctx, &testDoc{Title: "func"}))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		n, err := funcDocs.Count(ctx, where.NewWhere())
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+}
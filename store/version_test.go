@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moweilong/mo/errorsx"
+	"github.com/moweilong/mo/store/mixin"
+	"github.com/moweilong/mo/store/where"
+)
+
+type testArticle struct {
+	ID    uint   `gorm:"primaryKey"`
+	Title string `gorm:"size:64"`
+	mixin.Version
+}
+
+func TestStoreVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore[testArticle](NewReadWriteProvider(newTestDB(t, &testArticle{}), nil), nil, WithTenantExemption[testArticle]())
+	require.NoError(t, s.Create(ctx, &testArticle{Title: "draft"}))
+
+	first, err := s.Get(ctx, where.F("title", "draft"))
+	require.NoError(t, err)
+	stale, err := s.Get(ctx, where.F("id", first.ID))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.GetVersion())
+
+	first.Title = "published"
+	require.NoError(t, s.Update(ctx, first))
+	assert.Equal(t, int64(2), first.GetVersion())
+
+	// The stale copy was read with version 1 and must not overwrite the newer row.
+	stale.Title = "overwritten"
+	err = s.Update(ctx, stale)
+	require.ErrorIs(t, err, errorsx.ErrVersionConflict)
+	assert.Equal(t, http.StatusConflict, errorsx.Code(err))
+	assert.Equal(t, "VersionConflict", errorsx.Reason(err))
+	assert.Equal(t, int64(1), stale.GetVersion(), "the version of a rejected update is restored")
+
+	got, err := s.Get(ctx, where.F("id", first.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "published", got.Title)
+	assert.Equal(t, int64(2), got.GetVersion())
+}
@@ -0,0 +1,166 @@
+package where
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a keyset cursor is malformed or its signature does not match.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Types of cursor values whose JSON encoding alone is ambiguous.
+const (
+	cursorTypeTime  = "time"
+	cursorTypeInt   = "int"
+	cursorTypeUint  = "uint"
+	cursorTypeFloat = "float"
+)
+
+var (
+	cursorKeyMu sync.RWMutex
+	// cursorKey signs and verifies cursors. It defaults to a random per-process key,
+	// so services running several replicas must register a shared key.
+	cursorKey = randomCursorKey()
+)
+
+// cursorValue is the wire representation of a single sort-key value.
+type cursorValue struct {
+	Type  string `json:"t,omitempty"`
+	Value any    `json:"v"`
+}
+
+// RegisterCursorKey registers the secret used to sign and verify keyset cursors.
+func RegisterCursorKey(key []byte) {
+	cursorKeyMu.Lock()
+	defer cursorKeyMu.Unlock()
+	cursorKey = append([]byte(nil), key...)
+}
+
+// EncodeCursor encodes the sort-key values of a row into an opaque, signed, URL-safe cursor.
+// sort identifies the ordering the values were read for, e.g. "users:-created_at,id", and is
+// signed along with them, so that the cursor is only accepted for the same ordering.
+func EncodeCursor(sort string, values ...any) (string, error) {
+	fields := make([]cursorValue, len(values))
+	for i, v := range values {
+		fields[i] = newCursorValue(v)
+	}
+
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(sign(sort, payload), payload...)), nil
+}
+
+// newCursorValue tags v with its type where JSON would lose it, so that integers beyond the
+// precision of float64 survive the round trip.
+func newCursorValue(v any) cursorValue {
+	if t, ok := v.(time.Time); ok {
+		return cursorValue{Type: cursorTypeTime, Value: t.Format(time.RFC3339Nano)}
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Type: cursorTypeInt, Value: strconv.FormatInt(rv.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cursorValue{Type: cursorTypeUint, Value: strconv.FormatUint(rv.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: cursorTypeFloat, Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}
+	case reflect.Struct:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return newCursorValue(t)
+		}
+	}
+	return cursorValue{Value: v}
+}
+
+// DecodeCursor verifies the signature of cursor for the ordering sort, as given to
+// EncodeCursor, and returns the sort-key values it holds. Signed integers are returned as
+// int64, unsigned integers as uint64 and other numbers as float64.
+func DecodeCursor(cursor string, sort string) ([]any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) < sha256.Size {
+		return nil, ErrInvalidCursor
+	}
+
+	mac, payload := raw[:sha256.Size], raw[sha256.Size:]
+	if !hmac.Equal(mac, sign(sort, payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var fields []cursorValue
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if values[i], err = field.decode(); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return values, nil
+}
+
+// decode returns the value of a cursor field according to its type.
+func (c cursorValue) decode() (any, error) {
+	if c.Type == "" {
+		if n, ok := c.Value.(json.Number); ok {
+			return n.Float64()
+		}
+		return c.Value, nil
+	}
+
+	v, ok := c.Value.(string)
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	switch c.Type {
+	case cursorTypeTime:
+		return time.Parse(time.RFC3339Nano, v)
+	case cursorTypeInt:
+		return strconv.ParseInt(v, 10, 64)
+	case cursorTypeUint:
+		return strconv.ParseUint(v, 10, 64)
+	case cursorTypeFloat:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
+
+// sign computes the HMAC-SHA256 of the ordering sort and payload with the registered cursor key.
+func sign(sort string, payload []byte) []byte {
+	cursorKeyMu.RLock()
+	defer cursorKeyMu.RUnlock()
+
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(sort))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// randomCursorKey generates the default per-process cursor key.
+func randomCursorKey() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}
@@ -0,0 +1,70 @@
+package where
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeCursor(t *testing.T) {
+	ts := time.Date(2025, 10, 17, 8, 30, 0, 123456789, time.UTC)
+	id := uint(7)
+
+	cursor, err := EncodeCursor("users:-created_at,id", int64(9007199254740993), uint64(math.MaxUint64), "alice", ts, 1.5, float64(2), true, &id, nil)
+	require.NoError(t, err)
+
+	values, err := DecodeCursor(cursor, "users:-created_at,id")
+	require.NoError(t, err)
+	require.Len(t, values, 9)
+	assert.Equal(t, int64(9007199254740993), values[0])
+	assert.Equal(t, uint64(math.MaxUint64), values[1])
+	assert.Equal(t, "alice", values[2])
+	assert.True(t, ts.Equal(values[3].(time.Time)))
+	assert.Equal(t, 1.5, values[4])
+	assert.Equal(t, float64(2), values[5])
+	assert.Equal(t, true, values[6])
+	assert.Equal(t, uint64(7), values[7])
+	assert.Nil(t, values[8])
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	cursor, err := EncodeCursor("users:-id", int64(42))
+	require.NoError(t, err)
+
+	tampered := []byte(cursor)
+	tampered[len(tampered)-2] ^= 0x01
+
+	_, err = DecodeCursor(string(tampered), "users:-id")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = DecodeCursor("not-a-cursor", "users:-id")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestDecodeCursorRejectsOtherSort(t *testing.T) {
+	cursor, err := EncodeCursor("users:-id", int64(42))
+	require.NoError(t, err)
+
+	for _, sort := range []string{"users:id", "users:-age", "orders:-id", ""} {
+		_, err = DecodeCursor(cursor, sort)
+		assert.ErrorIs(t, err, ErrInvalidCursor, sort)
+	}
+}
+
+func TestRegisterCursorKey(t *testing.T) {
+	RegisterCursorKey([]byte("key-1"))
+	cursor, err := EncodeCursor("users:-id", int64(1))
+	require.NoError(t, err)
+
+	RegisterCursorKey([]byte("key-2"))
+	_, err = DecodeCursor(cursor, "users:-id")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	RegisterCursorKey([]byte("key-1"))
+	values, err := DecodeCursor(cursor, "users:-id")
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(1)}, values)
+}
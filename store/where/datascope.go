@@ -0,0 +1,50 @@
+package where
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMissingDataScope is returned when data scopes are enforced but the context carries no scope.
+var ErrMissingDataScope = errors.New("missing data scope in context")
+
+// DataScopeLevel is the extent of the rows a caller may access.
+type DataScopeLevel string
+
+const (
+	// DataScopeAll grants access to every row.
+	DataScopeAll DataScopeLevel = "all"
+	// DataScopeDeptAndChildren grants access to the rows of the caller's department and of its descendants.
+	DataScopeDeptAndChildren DataScopeLevel = "dept_and_children"
+	// DataScopeDept grants access to the rows of the caller's department.
+	DataScopeDept DataScopeLevel = "dept"
+	// DataScopeSelf grants access to the rows owned by the caller.
+	DataScopeSelf DataScopeLevel = "self"
+)
+
+// DataScope is the row-level data permission of a caller, restricting rows by owner or
+// department on top of tenant isolation.
+type DataScope struct {
+	Level DataScopeLevel
+	// UserID is the ID of the caller, matched against the owner column for DataScopeSelf.
+	UserID any
+	// DeptID is the ID of the caller's department, matched against the department column.
+	DeptID any
+	// ChildDeptIDs are the IDs of the descendants of the caller's department, also accessible
+	// with DataScopeDeptAndChildren. They are resolved by the caller, e.g. when authenticating.
+	ChildDeptIDs []any
+}
+
+// dataScopeKey is the context key of the data scope.
+type dataScopeKey struct{}
+
+// WithDataScope returns a copy of ctx carrying the data scope of the caller.
+func WithDataScope(ctx context.Context, scope DataScope) context.Context {
+	return context.WithValue(ctx, dataScopeKey{}, scope)
+}
+
+// DataScopeFromContext returns the data scope carried by ctx, if any.
+func DataScopeFromContext(ctx context.Context) (DataScope, bool) {
+	scope, ok := ctx.Value(dataScopeKey{}).(DataScope)
+	return scope, ok
+}
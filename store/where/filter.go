@@ -0,0 +1,456 @@
+package where
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moweilong/mo/stringcase"
+)
+
+const (
+	// FilterDelimiter separates the field, JSON key, date part and operator of a filter key.
+	FilterDelimiter = "__"
+	// FilterJSONFieldDelimiter separates a JSON column from the path inside it.
+	FilterJSONFieldDelimiter = "."
+
+	// likeEscape is the escape character of LIKE patterns, chosen because it needs
+	// no escaping in string literals of any supported dialect.
+	likeEscape = "!"
+)
+
+// filterOps lists the operators of the filter DSL, matching entx/query.
+var filterOps = map[string]struct{}{
+	"not": {}, "in": {}, "not_in": {}, "gte": {}, "gt": {}, "lte": {}, "lt": {}, "range": {},
+	"isnull": {}, "not_isnull": {}, "contains": {}, "icontains": {}, "startswith": {}, "istartswith": {},
+	"endswith": {}, "iendswith": {}, "exact": {}, "iexact": {}, "regex": {}, "iregex": {}, "search": {},
+}
+
+// filterDateParts lists the date parts of the filter DSL, matching entx/query.
+var filterDateParts = map[string]struct{}{
+	"date": {}, "year": {}, "iso_year": {}, "quarter": {}, "month": {}, "week": {}, "week_day": {},
+	"iso_week_day": {}, "day": {}, "time": {}, "hour": {}, "minute": {}, "second": {}, "microsecond": {},
+}
+
+// FromFilterJSON builds Options from the Django-style filter DSL accepted by entx/query, so that
+// services on GORM and services on ent accept identical list-query parameters. Both arguments are
+// a JSON object (or an array of objects) mapping filter keys to string values; the conditions of
+// andFilterJSON are combined with AND, those of orFilterJSON with OR. A filter key has the form
+//
+//	field[.json.path][__json_key][__date_part][__operator]
+//
+// e.g. "name__icontains", "created_at__year__gte" or "preferences.daily_email". Field names are
+// converted to snake case and always quoted, so user-provided keys cannot inject SQL.
+func FromFilterJSON(andFilterJSON, orFilterJSON string) (*Options, error) {
+	whr := NewWhere()
+
+	ands, err := parseFilterJSON(andFilterJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(ands) > 0 {
+		whr.C(clause.And(ands...))
+	}
+
+	ors, err := parseFilterJSON(orFilterJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(ors) > 0 {
+		whr.C(clause.Or(ors...))
+	}
+
+	return whr, nil
+}
+
+// FilterColumns returns the columns referenced by the filter DSL conditions of whr, as added by
+// FromFilterJSON, so that they can be checked against the columns clients may filter by.
+func (whr *Options) FilterColumns() []string {
+	var columns []string
+	var walk func(exprs []clause.Expression)
+	walk = func(exprs []clause.Expression) {
+		for _, expr := range exprs {
+			switch expr := expr.(type) {
+			case filterCondition:
+				columns = append(columns, expr.Operand.Column)
+			case clause.AndConditions:
+				walk(expr.Exprs)
+			case clause.OrConditions:
+				walk(expr.Exprs)
+			case clause.NotConditions:
+				walk(expr.Exprs)
+			}
+		}
+	}
+	walk(whr.Clauses)
+	return columns
+}
+
+// parseFilterJSON parses a JSON object, or an array of objects, of filters into conditions.
+func parseFilterJSON(filterJSON string) ([]clause.Expression, error) {
+	if filterJSON == "" {
+		return nil, nil
+	}
+
+	var filters []map[string]string
+	var filter map[string]string
+	if err := json.Unmarshal([]byte(filterJSON), &filter); err == nil {
+		filters = append(filters, filter)
+	} else if err := json.Unmarshal([]byte(filterJSON), &filters); err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", filterJSON, err)
+	}
+
+	var conds []clause.Expression
+	for _, filter := range filters {
+		for key, value := range filter {
+			cond, err := parseFilter(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if cond != nil {
+				conds = append(conds, cond)
+			}
+		}
+	}
+	return conds, nil
+}
+
+// parseFilter parses a single filter. Filters with an empty field or value, or an unknown
+// operator, are ignored like entx/query does.
+func parseFilter(key, value string) (clause.Expression, error) {
+	keys := strings.Split(key, FilterDelimiter)
+	if keys[0] == "" || value == "" {
+		return nil, nil
+	}
+
+	operand := newFilterOperand(keys[0])
+	op := ""
+	switch len(keys) {
+	case 1:
+	case 2:
+		part := strings.ToLower(keys[1])
+		if _, ok := filterOps[part]; ok {
+			op = part
+		} else if _, ok := filterDateParts[part]; ok {
+			operand.DatePart = part
+		} else if operand.JSONPath == "" {
+			operand.JSONPath = stringcase.ToSnakeCase(keys[1])
+		} else {
+			return nil, nil
+		}
+	case 3:
+		part, last := strings.ToLower(keys[1]), strings.ToLower(keys[2])
+		if _, ok := filterDateParts[part]; ok {
+			operand.DatePart = part
+			if _, ok := filterOps[last]; !ok {
+				return nil, nil
+			}
+			op = last
+			break
+		}
+
+		if operand.JSONPath != "" {
+			return nil, nil
+		}
+		operand.JSONPath = stringcase.ToSnakeCase(keys[1])
+		if _, ok := filterOps[last]; ok {
+			op = last
+		} else if _, ok := filterDateParts[last]; ok {
+			operand.DatePart = last
+		} else {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	cond := filterCondition{Operand: operand, Op: op, Value: value}
+	switch op {
+	case "in", "not_in", "range":
+		if err := json.Unmarshal([]byte(value), &cond.Values); err != nil {
+			return nil, fmt.Errorf("invalid value of filter %q: %w", key, err)
+		}
+		if op == "range" && len(cond.Values) != 2 {
+			return nil, fmt.Errorf("invalid value of filter %q: range requires two values", key)
+		}
+	}
+	return cond, nil
+}
+
+// filterOperand is the left-hand side of a filter condition: a column, optionally narrowed
+// to a path inside a JSON column and to a part of a date.
+type filterOperand struct {
+	Column   string
+	JSONPath string
+	DatePart string
+}
+
+// newFilterOperand parses the field of a filter key, e.g. "name" or "preferences.daily_email".
+func newFilterOperand(field string) filterOperand {
+	column, path, _ := strings.Cut(field, FilterJSONFieldDelimiter)
+	operand := filterOperand{Column: stringcase.ToSnakeCase(column)}
+	if path != "" {
+		parts := strings.Split(path, FilterJSONFieldDelimiter)
+		for i := range parts {
+			parts[i] = stringcase.ToSnakeCase(parts[i])
+		}
+		operand.JSONPath = strings.Join(parts, FilterJSONFieldDelimiter)
+	}
+	return operand
+}
+
+// Build writes the operand for the dialect of the statement.
+func (o filterOperand) Build(builder clause.Builder) {
+	if o.DatePart == "" {
+		o.buildValue(builder)
+		return
+	}
+
+	switch dialectOf(builder) {
+	case "postgres":
+		switch o.DatePart {
+		case "date", "time":
+			builder.WriteString("CAST(")
+			o.buildValue(builder)
+			builder.WriteString(" AS " + strings.ToUpper(o.DatePart) + ")")
+		case "week_day":
+			// Sunday is 1, like MySQL DAYOFWEEK and Django.
+			builder.WriteString("(EXTRACT(DOW FROM ")
+			o.buildValue(builder)
+			builder.WriteString(") + 1)")
+		default:
+			builder.WriteString("EXTRACT(" + postgresDateParts[o.DatePart] + " FROM ")
+			o.buildValue(builder)
+			builder.WriteString(")")
+		}
+	case "sqlite":
+		format, ok := sqliteDateParts[o.DatePart]
+		if !ok {
+			_ = builder.AddError(fmt.Errorf("date part %q is not supported by sqlite", o.DatePart))
+			return
+		}
+		switch o.DatePart {
+		case "date", "time":
+			builder.WriteString(strings.ToUpper(o.DatePart) + "(")
+			o.buildValue(builder)
+			builder.WriteString(")")
+		default:
+			builder.WriteString("(CAST(STRFTIME('" + format + "', ")
+			o.buildValue(builder)
+			builder.WriteString(") AS INTEGER)")
+			if o.DatePart == "week_day" {
+				builder.WriteString(" + 1")
+			}
+			builder.WriteString(")")
+		}
+	default:
+		switch o.DatePart {
+		case "iso_year":
+			builder.WriteString("(YEARWEEK(")
+			o.buildValue(builder)
+			builder.WriteString(", 3) DIV 100)")
+		case "week":
+			builder.WriteString("WEEK(")
+			o.buildValue(builder)
+			builder.WriteString(", 3)")
+		case "iso_week_day":
+			builder.WriteString("(WEEKDAY(")
+			o.buildValue(builder)
+			builder.WriteString(") + 1)")
+		default:
+			builder.WriteString(mysqlDateParts[o.DatePart] + "(")
+			o.buildValue(builder)
+			builder.WriteString(")")
+		}
+	}
+}
+
+// buildValue writes the column, or the text at JSONPath inside it.
+func (o filterOperand) buildValue(builder clause.Builder) {
+	column := clause.Column{Table: clause.CurrentTable, Name: o.Column}
+	if o.JSONPath == "" {
+		builder.WriteQuoted(column)
+		return
+	}
+
+	switch dialectOf(builder) {
+	case "postgres":
+		builder.WriteString("(")
+		builder.WriteQuoted(column)
+		builder.WriteString(" #>> ")
+		builder.AddVar(builder, "{"+strings.ReplaceAll(o.JSONPath, FilterJSONFieldDelimiter, ",")+"}")
+		builder.WriteString(")")
+	case "sqlite":
+		builder.WriteString("JSON_EXTRACT(")
+		builder.WriteQuoted(column)
+		builder.WriteString(", ")
+		builder.AddVar(builder, "$."+o.JSONPath)
+		builder.WriteString(")")
+	default:
+		builder.WriteString("JSON_UNQUOTE(JSON_EXTRACT(")
+		builder.WriteQuoted(column)
+		builder.WriteString(", ")
+		builder.AddVar(builder, "$."+o.JSONPath)
+		builder.WriteString("))")
+	}
+}
+
+var (
+	postgresDateParts = map[string]string{
+		"year": "YEAR", "iso_year": "ISOYEAR", "quarter": "QUARTER", "month": "MONTH", "week": "WEEK",
+		"iso_week_day": "ISODOW", "day": "DAY", "hour": "HOUR", "minute": "MINUTE", "second": "SECOND",
+		"microsecond": "MICROSECONDS",
+	}
+	mysqlDateParts = map[string]string{
+		"date": "DATE", "year": "YEAR", "quarter": "QUARTER", "month": "MONTH", "week_day": "DAYOFWEEK",
+		"day": "DAY", "time": "TIME", "hour": "HOUR", "minute": "MINUTE", "second": "SECOND",
+		"microsecond": "MICROSECOND",
+	}
+	sqliteDateParts = map[string]string{
+		"date": "", "time": "", "year": "%Y", "month": "%m", "week_day": "%w", "day": "%d",
+		"hour": "%H", "minute": "%M", "second": "%S",
+	}
+)
+
+// filterCondition is a condition of the filter DSL, rendered for the dialect of the statement.
+type filterCondition struct {
+	Operand filterOperand
+	Op      string
+	Value   string
+	Values  []any
+}
+
+// Build implements clause.Expression.
+func (c filterCondition) Build(builder clause.Builder) {
+	dialect := dialectOf(builder)
+
+	switch c.Op {
+	case "", "exact":
+		c.binary(builder, " = ", c.Value)
+	case "not":
+		builder.WriteString("NOT (")
+		c.binary(builder, " = ", c.Value)
+		builder.WriteString(")")
+	case "in", "not_in":
+		c.Operand.Build(builder)
+		if c.Op == "in" {
+			builder.WriteString(" IN ")
+		} else {
+			builder.WriteString(" NOT IN ")
+		}
+		builder.AddVar(builder, c.Values)
+	case "gte":
+		c.binary(builder, " >= ", c.Value)
+	case "gt":
+		c.binary(builder, " > ", c.Value)
+	case "lte":
+		c.binary(builder, " <= ", c.Value)
+	case "lt":
+		c.binary(builder, " < ", c.Value)
+	case "range":
+		builder.WriteString("(")
+		c.binary(builder, " >= ", c.Values[0])
+		builder.WriteString(" AND ")
+		c.binary(builder, " <= ", c.Values[1])
+		builder.WriteString(")")
+	case "isnull":
+		c.Operand.Build(builder)
+		builder.WriteString(" IS NULL")
+	case "not_isnull":
+		c.Operand.Build(builder)
+		builder.WriteString(" IS NOT NULL")
+	case "contains", "search":
+		c.like(builder, dialect, "%"+escapeLike(c.Value)+"%", false)
+	case "icontains":
+		c.like(builder, dialect, "%"+escapeLike(c.Value)+"%", true)
+	case "startswith":
+		c.like(builder, dialect, escapeLike(c.Value)+"%", false)
+	case "istartswith":
+		c.like(builder, dialect, escapeLike(c.Value)+"%", true)
+	case "endswith":
+		c.like(builder, dialect, "%"+escapeLike(c.Value), false)
+	case "iendswith":
+		c.like(builder, dialect, "%"+escapeLike(c.Value), true)
+	case "iexact":
+		c.like(builder, dialect, escapeLike(c.Value), true)
+	case "regex", "iregex":
+		c.regex(builder, dialect)
+	}
+}
+
+// binary writes "operand op value".
+func (c filterCondition) binary(builder clause.Builder, op string, value any) {
+	c.Operand.Build(builder)
+	builder.WriteString(op)
+	builder.AddVar(builder, value)
+}
+
+// like writes a LIKE match of pattern, case-insensitive when fold is set.
+func (c filterCondition) like(builder clause.Builder, dialect string, pattern string, fold bool) {
+	switch {
+	case !fold:
+		c.Operand.Build(builder)
+		builder.WriteString(" LIKE ")
+	case dialect == "postgres":
+		c.Operand.Build(builder)
+		builder.WriteString(" ILIKE ")
+	default:
+		builder.WriteString("LOWER(")
+		c.Operand.Build(builder)
+		builder.WriteString(") LIKE ")
+		pattern = strings.ToLower(pattern)
+	}
+	builder.AddVar(builder, pattern)
+	builder.WriteString(" ESCAPE '" + likeEscape + "'")
+}
+
+// regex writes a regular expression match. MySQL uses REGEXP_LIKE with an explicit match
+// type, since the REGEXP BINARY form is deprecated.
+func (c filterCondition) regex(builder clause.Builder, dialect string) {
+	fold := c.Op == "iregex"
+	value := c.Value
+
+	switch dialect {
+	case "postgres":
+		c.Operand.Build(builder)
+		if fold {
+			builder.WriteString(" ~* ")
+		} else {
+			builder.WriteString(" ~ ")
+		}
+		builder.AddVar(builder, value)
+	case "sqlite":
+		c.Operand.Build(builder)
+		builder.WriteString(" REGEXP ")
+		if fold && !strings.HasPrefix(value, "(?i)") {
+			value = "(?i)" + value
+		}
+		builder.AddVar(builder, value)
+	default:
+		builder.WriteString("REGEXP_LIKE(")
+		c.Operand.Build(builder)
+		builder.WriteString(", ")
+		builder.AddVar(builder, value)
+		if fold {
+			builder.WriteString(", 'i')")
+		} else {
+			builder.WriteString(", 'c')")
+		}
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(value)
+}
+
+// dialectOf returns the name of the dialect the statement is built for.
+func dialectOf(builder clause.Builder) string {
+	if stmt, ok := builder.(*gorm.Statement); ok && stmt.Dialector != nil {
+		return stmt.Dialector.Name()
+	}
+	return ""
+}
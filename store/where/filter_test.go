@@ -0,0 +1,126 @@
+package where
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type filterUser struct {
+	ID          uint
+	Name        string
+	Preferences string
+}
+
+func dryRun(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return db
+}
+
+func filterSQL(t *testing.T, db *gorm.DB, and, or string) string {
+	opts, err := FromFilterJSON(and, or)
+	require.NoError(t, err)
+	return strings.TrimSpace(db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return opts.Where(tx).Find(&[]filterUser{})
+	}))
+}
+
+func TestFromFilterJSON(t *testing.T) {
+	my := dryRun(t, mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:3306)/db", SkipInitializeWithVersion: true}))
+	pg := dryRun(t, postgres.New(postgres.Config{DSN: "host=127.0.0.1 user=u dbname=db"}))
+
+	tests := []struct {
+		name  string
+		and   string
+		or    string
+		mysql string
+		pg    string
+	}{
+		{
+			name:  "equal",
+			and:   `{"name":"tom"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE `filter_users`.`name` = 'tom'",
+			pg:    `SELECT * FROM "filter_users" WHERE "filter_users"."name" = 'tom'`,
+		},
+		{
+			name:  "in",
+			and:   `{"id__in":"[1,2]"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE `filter_users`.`id` IN (1,2)",
+			pg:    `SELECT * FROM "filter_users" WHERE "filter_users"."id" IN (1,2)`,
+		},
+		{
+			name:  "icontains escapes wildcards",
+			and:   `{"name__icontains":"50%_Off"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE LOWER(`filter_users`.`name`) LIKE '%50!%!_off%' ESCAPE '!'",
+			pg:    `SELECT * FROM "filter_users" WHERE "filter_users"."name" ILIKE '%50!%!_Off%' ESCAPE '!'`,
+		},
+		{
+			name:  "json field",
+			and:   `{"preferences.dailyEmail":"true"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE JSON_UNQUOTE(JSON_EXTRACT(`filter_users`.`preferences`, '$.daily_email')) = 'true'",
+			pg:    `SELECT * FROM "filter_users" WHERE ("filter_users"."preferences" #>> '{daily_email}') = 'true'`,
+		},
+		{
+			name:  "date part",
+			and:   `{"created_at__year__gte":"2024"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE YEAR(`filter_users`.`created_at`) >= '2024'",
+			pg:    `SELECT * FROM "filter_users" WHERE EXTRACT(YEAR FROM "filter_users"."created_at") >= '2024'`,
+		},
+		{
+			name:  "and with or",
+			and:   `[{"id__gt":"1"}]`,
+			or:    `[{"name__isnull":"true"},{"name__regex":"^t"}]`,
+			mysql: "SELECT * FROM `filter_users` WHERE `filter_users`.`id` > '1' AND (`filter_users`.`name` IS NULL OR REGEXP_LIKE(`filter_users`.`name`, '^t', 'c'))",
+			pg:    `SELECT * FROM "filter_users" WHERE "filter_users"."id" > '1' AND ("filter_users"."name" IS NULL OR "filter_users"."name" ~ '^t')`,
+		},
+		{
+			name:  "iregex",
+			and:   `{"name__iregex":"^t"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE REGEXP_LIKE(`filter_users`.`name`, '^t', 'i')",
+			pg:    `SELECT * FROM "filter_users" WHERE "filter_users"."name" ~* '^t'`,
+		},
+		{
+			name:  "field names are sanitized",
+			and:   `{"name\"; DROP TABLE x; --":"1"}`,
+			mysql: "SELECT * FROM `filter_users` WHERE `filter_users`.`name_drop_table_x` = '1'",
+			pg:    `SELECT * FROM "filter_users" WHERE "filter_users"."name_drop_table_x" = '1'`,
+		},
+		{
+			name:  "empty values and unknown operators are ignored",
+			and:   `{"name":"","id__unknown__op__x":"1"}`,
+			mysql: "SELECT * FROM `filter_users`",
+			pg:    `SELECT * FROM "filter_users"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.mysql, filterSQL(t, my, tt.and, tt.or))
+			assert.Equal(t, tt.pg, filterSQL(t, pg, tt.and, tt.or))
+		})
+	}
+}
+
+func TestFromFilterJSONInvalid(t *testing.T) {
+	_, err := FromFilterJSON(`{"name":`, "")
+	assert.Error(t, err)
+
+	_, err = FromFilterJSON(`{"id__in":"1,2"}`, "")
+	assert.Error(t, err)
+
+	_, err = FromFilterJSON("", `{"id__range":"[1]"}`)
+	assert.Error(t, err)
+}
+
+func TestFilterColumns(t *testing.T) {
+	opts, err := FromFilterJSON(`{"name__icontains":"tom","createdAt__year__gte":"2024"}`, `[{"preferences.dailyEmail":"true"}]`)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"name", "created_at", "preferences"}, opts.Q("id > ?", 1).FilterColumns())
+
+	assert.Empty(t, NewWhere().F("name", "tom").FilterColumns())
+}
@@ -0,0 +1,98 @@
+package where
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrMissingTenant is returned when tenant isolation is enforced but the context carries no tenant value.
+var ErrMissingTenant = errors.New("missing tenant in context")
+
+// ErrTenantMismatch is returned when an object is written with a tenant value other than the one in the context.
+var ErrTenantMismatch = errors.New("object belongs to another tenant")
+
+// Tenant represents a tenant dimension with a key and a function to retrieve its value.
+type Tenant struct {
+	Key       string                           // The column associated with the tenant dimension
+	ValueFunc func(ctx context.Context) string // Function to retrieve the tenant's value based on the context
+}
+
+// skipTenantKey is the context key that disables tenant isolation.
+type skipTenantKey struct{}
+
+var (
+	tenantsMu sync.RWMutex
+	// registeredTenants holds the registered tenant dimensions, in registration order.
+	registeredTenants []Tenant
+)
+
+// RegisterTenant registers a tenant dimension with the specified key and value function.
+// Several dimensions (e.g. org_id and project_id) can be registered; registering an
+// existing key replaces its value function.
+func RegisterTenant(key string, valueFunc func(context.Context) string) {
+	if key == "" || valueFunc == nil {
+		return
+	}
+
+	tenantsMu.Lock()
+	defer tenantsMu.Unlock()
+
+	for i := range registeredTenants {
+		if registeredTenants[i].Key == key {
+			registeredTenants[i].ValueFunc = valueFunc
+			return
+		}
+	}
+	registeredTenants = append(registeredTenants, Tenant{Key: key, ValueFunc: valueFunc})
+}
+
+// RegisteredTenants returns the registered tenant dimensions.
+func RegisteredTenants() []Tenant {
+	tenantsMu.RLock()
+	defer tenantsMu.RUnlock()
+	return append([]Tenant(nil), registeredTenants...)
+}
+
+// WithoutTenant returns a copy of ctx for which tenant isolation is not enforced.
+// It is meant for trusted code paths such as administrative jobs.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTenantKey{}, true)
+}
+
+// TenantSkipped reports whether tenant isolation is disabled for ctx.
+func TenantSkipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(skipTenantKey{}).(bool)
+	return skipped
+}
+
+// TenantValues returns the value of every registered tenant dimension not listed in exempt,
+// keyed by tenant key. It returns ErrMissingTenant if any of them is empty in ctx.
+func TenantValues(ctx context.Context, exempt ...string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, tenant := range RegisteredTenants() {
+		if slices.Contains(exempt, tenant.Key) {
+			continue
+		}
+
+		value := tenant.ValueFunc(ctx)
+		if value == "" {
+			return nil, fmt.Errorf("%w: %s", ErrMissingTenant, tenant.Key)
+		}
+		values[tenant.Key] = value
+	}
+	return values, nil
+}
+
+// TenantValue returns the value in ctx of the tenant dimension registered under key, or ""
+// when the dimension is not registered or ctx carries no value for it.
+func TenantValue(ctx context.Context, key string) string {
+	for _, tenant := range RegisteredTenants() {
+		if tenant.Key == key {
+			return tenant.ValueFunc(ctx)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package where
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantCtxKey string
+
+func tenantFromContext(key string) func(ctx context.Context) string {
+	return func(ctx context.Context) string {
+		value, _ := ctx.Value(tenantCtxKey(key)).(string)
+		return value
+	}
+}
+
+func TestTenantValues(t *testing.T) {
+	RegisterTenant("org_id", tenantFromContext("org"))
+	RegisterTenant("project_id", tenantFromContext("project"))
+	RegisterTenant("org_id", tenantFromContext("org")) // Re-registering replaces the dimension.
+	require.Len(t, RegisteredTenants(), 2)
+
+	ctx := context.WithValue(context.Background(), tenantCtxKey("org"), "o1")
+
+	_, err := TenantValues(ctx)
+	assert.ErrorIs(t, err, ErrMissingTenant)
+
+	values, err := TenantValues(ctx, "project_id")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"org_id": "o1"}, values)
+
+	ctx = context.WithValue(ctx, tenantCtxKey("project"), "p1")
+	values, err = TenantValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"org_id": "o1", "project_id": "p1"}, values)
+
+	assert.Equal(t, map[any]any{"org_id": "o1", "project_id": "p1"}, T(ctx).Filters)
+
+	assert.False(t, TenantSkipped(ctx))
+	assert.True(t, TenantSkipped(WithoutTenant(ctx)))
+}
@@ -2,6 +2,7 @@ package where
 
 import (
 	"context"
+	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -12,12 +13,6 @@ const (
 	defaultLimit = -1
 )
 
-// Tenant represents a tenant with a key and a function to retrieve its value.
-type Tenant struct {
-	Key       string                           // The key associated with the tenant
-	ValueFunc func(ctx context.Context) string // Function to retrieve the tenant's value based on the context
-}
-
 // Where defines an interface for types that can modify GORM database queries.
 type Where interface {
 	Where(db *gorm.DB) *gorm.DB
@@ -35,6 +30,22 @@ type Query struct {
 	Args []interface{}
 }
 
+// Sort represents a single sort specification of a query.
+type Sort struct {
+	// Column is the column or model field name to sort by.
+	Column string `json:"column"`
+	// Desc reports whether the column is sorted in descending order.
+	Desc bool `json:"desc"`
+}
+
+// Relation is an association loaded by queries returning rows.
+type Relation struct {
+	// Name is the association field name, with nested associations separated by dots, e.g. "Orders.Items".
+	Name string
+	// Conds are the conditions on the associated rows, as accepted by gorm.DB.Preload and gorm.DB.Joins.
+	Conds []interface{}
+}
+
 // Option defines a function type that modifies Options.
 type Option func(*Options)
 
@@ -52,11 +63,39 @@ type Options struct {
 	Clauses []clause.Expression
 	// Queries contains a list of queries to be executed.
 	Queries []Query
+	// Sorts contains the sort specifications applied by list operations, in priority order.
+	// +optional
+	Sorts []Sort `json:"sorts,omitempty"`
+	// Cursor is the opaque keyset cursor used by Store.ListPage instead of Offset.
+	// +optional
+	Cursor string `json:"cursor,omitempty"`
+	// Backward reports whether Cursor selects the page before it rather than after it.
+	// +optional
+	Backward bool `json:"backward,omitempty"`
+	// SkipCount disables the COUNT query issued by Store.List and Store.ListPage.
+	// +optional
+	SkipCount bool `json:"skipCount,omitempty"`
+	// IncludeDeleted makes the query also match soft-deleted rows.
+	// +optional
+	IncludeDeleted bool `json:"includeDeleted,omitempty"`
+	// GroupBy lists the columns the aggregate queries of Store.Aggregate are grouped by.
+	// +optional
+	GroupBy []string `json:"groupBy,omitempty"`
+	// Having contains the conditions on the groups of aggregate queries.
+	// +optional
+	Having []Query
+	// Preloads contains the associations loaded with separate queries by Store.Get, List and ListPage.
+	// +optional
+	Preloads []Relation
+	// Joined contains the associations loaded with a JOIN by Store.Get, List and ListPage.
+	// Conditions may then reference their columns, e.g. "Company.name = ?".
+	// +optional
+	Joined []Relation
+	// Selects restricts the columns read by Store.Get, List and ListPage.
+	// +optional
+	Selects []string `json:"selects,omitempty"`
 }
 
-// tenant holds the registered tenant instance.
-var registeredTenant Tenant
-
 // WithOffset initializes the Offset field in Options with the given offset value.
 func WithOffset(offset int64) Option {
 	return func(whr *Options) {
@@ -116,6 +155,77 @@ func WithQuery(query interface{}, args ...interface{}) Option {
 	}
 }
 
+// WithSort appends sort specifications to the Sorts field in Options.
+// See Options.S for the accepted syntax.
+func WithSort(keys ...string) Option {
+	return func(whr *Options) {
+		whr.S(keys...)
+	}
+}
+
+// WithAfter sets the keyset cursor to select the page after the given cursor.
+func WithAfter(cursor string) Option {
+	return func(whr *Options) {
+		whr.After(cursor)
+	}
+}
+
+// WithBefore sets the keyset cursor to select the page before the given cursor.
+func WithBefore(cursor string) Option {
+	return func(whr *Options) {
+		whr.Before(cursor)
+	}
+}
+
+// WithoutCount disables the COUNT query of list operations.
+func WithoutCount() Option {
+	return func(whr *Options) {
+		whr.SkipCount = true
+	}
+}
+
+// WithDeleted makes the query also match soft-deleted rows.
+func WithDeleted() Option {
+	return func(whr *Options) {
+		whr.IncludeDeleted = true
+	}
+}
+
+// WithGroupBy sets the columns aggregate queries are grouped by.
+func WithGroupBy(columns ...string) Option {
+	return func(whr *Options) {
+		whr.G(columns...)
+	}
+}
+
+// WithHaving adds a condition on the groups of aggregate queries.
+func WithHaving(query interface{}, args ...interface{}) Option {
+	return func(whr *Options) {
+		whr.H(query, args...)
+	}
+}
+
+// WithPreload adds an association loaded with a separate query, optionally filtered by conds.
+func WithPreload(name string, conds ...interface{}) Option {
+	return func(whr *Options) {
+		whr.Preload(name, conds...)
+	}
+}
+
+// WithJoins adds an association loaded with a JOIN, optionally filtered by conds.
+func WithJoins(name string, conds ...interface{}) Option {
+	return func(whr *Options) {
+		whr.Joins(name, conds...)
+	}
+}
+
+// WithSelect restricts the columns read by queries returning rows.
+func WithSelect(columns ...string) Option {
+	return func(whr *Options) {
+		whr.Select(columns...)
+	}
+}
+
 // NewWhere constructs a new Options object, applying the given where options.
 func NewWhere(opts ...Option) *Options {
 	whr := &Options{
@@ -176,10 +286,84 @@ func (whr *Options) Q(query interface{}, args ...interface{}) *Options {
 	return whr
 }
 
-// T retrieves the value associated with the registered tenant using the provided context.
+// S adds sort specifications to the query. A key prefixed with "-" sorts in descending
+// order, otherwise in ascending order, e.g. S("-created_at", "name"). Empty keys are ignored.
+func (whr *Options) S(keys ...string) *Options {
+	for _, key := range keys {
+		desc := strings.HasPrefix(key, "-")
+		column := strings.TrimPrefix(key, "-")
+		if column == "" {
+			continue
+		}
+		whr.Sorts = append(whr.Sorts, Sort{Column: column, Desc: desc})
+	}
+	return whr
+}
+
+// After selects the page that follows the row encoded in cursor.
+// An empty cursor selects the first page.
+func (whr *Options) After(cursor string) *Options {
+	whr.Cursor = cursor
+	whr.Backward = false
+	return whr
+}
+
+// Before selects the page that precedes the row encoded in cursor.
+func (whr *Options) Before(cursor string) *Options {
+	whr.Cursor = cursor
+	whr.Backward = true
+	return whr
+}
+
+// NoCount disables the COUNT query of list operations.
+func (whr *Options) NoCount() *Options {
+	whr.SkipCount = true
+	return whr
+}
+
+// D makes the query also match soft-deleted rows.
+func (whr *Options) D() *Options {
+	whr.IncludeDeleted = true
+	return whr
+}
+
+// G adds columns aggregate queries are grouped by.
+func (whr *Options) G(columns ...string) *Options {
+	whr.GroupBy = append(whr.GroupBy, columns...)
+	return whr
+}
+
+// H adds a condition on the groups of aggregate queries, e.g. H("total > ?", 100).
+// Aggregates are referenced by their alias.
+func (whr *Options) H(query interface{}, args ...interface{}) *Options {
+	whr.Having = append(whr.Having, Query{Query: query, Args: args})
+	return whr
+}
+
+// Preload adds an association loaded with a separate query, optionally filtered by conds,
+// e.g. Preload("Orders", "status = ?", "paid") or Preload("Orders.Items").
+func (whr *Options) Preload(name string, conds ...interface{}) *Options {
+	whr.Preloads = append(whr.Preloads, Relation{Name: name, Conds: conds})
+	return whr
+}
+
+// Joins adds an association loaded with a JOIN, optionally filtered by conds.
+// Only has-one and belongs-to associations can be joined.
+func (whr *Options) Joins(name string, conds ...interface{}) *Options {
+	whr.Joined = append(whr.Joined, Relation{Name: name, Conds: conds})
+	return whr
+}
+
+// Select restricts the columns read by queries returning rows to the given column or field names.
+func (whr *Options) Select(columns ...string) *Options {
+	whr.Selects = append(whr.Selects, columns...)
+	return whr
+}
+
+// T adds a filter for every registered tenant, using the tenant values carried by ctx.
 func (whr *Options) T(ctx context.Context) *Options {
-	if registeredTenant.Key != "" && registeredTenant.ValueFunc != nil {
-		whr.F(registeredTenant.Key, registeredTenant.ValueFunc(ctx))
+	for _, tenant := range RegisteredTenants() {
+		whr.F(tenant.Key, tenant.ValueFunc(ctx))
 	}
 	return whr
 }
@@ -201,12 +385,19 @@ func (whr *Options) F(kvs ...any) *Options {
 }
 
 // Where applies the filters and clauses to the given gorm.DB instance.
+// GroupBy and Having are left to aggregate queries, and Preloads, Joined and Selects to
+// queries returning rows. It does not modify whr, so the same Options can be applied to several queries.
 func (whr *Options) Where(db *gorm.DB) *gorm.DB {
+	clauses := append([]clause.Expression(nil), whr.Clauses...)
 	for _, query := range whr.Queries {
 		conds := db.Statement.BuildCondition(query.Query, query.Args...)
-		whr.Clauses = append(whr.Clauses, conds...)
+		clauses = append(clauses, conds...)
 	}
-	return db.Where(whr.Filters).Clauses(whr.Clauses...).Offset(whr.Offset).Limit(whr.Limit)
+	if whr.IncludeDeleted {
+		// Lift GORM's own soft delete scope for models using gorm.DeletedAt.
+		db = db.Unscoped()
+	}
+	return db.Where(whr.Filters).Clauses(clauses...).Offset(whr.Offset).Limit(whr.Limit)
 }
 
 // O is a convenience function to create a new Options with offset.
@@ -229,20 +420,42 @@ func C(conds ...clause.Expression) *Options {
 	return NewWhere().C(conds...)
 }
 
+// S is a convenience function to create a new Options with sort specifications.
+func S(keys ...string) *Options {
+	return NewWhere().S(keys...)
+}
+
+// D is a convenience function to create a new Options that also matches soft-deleted rows.
+func D() *Options {
+	return NewWhere().D()
+}
+
+// Preload is a convenience function to create a new Options preloading an association.
+func Preload(name string, conds ...interface{}) *Options {
+	return NewWhere().Preload(name, conds...)
+}
+
+// Joins is a convenience function to create a new Options joining an association.
+func Joins(name string, conds ...interface{}) *Options {
+	return NewWhere().Joins(name, conds...)
+}
+
+// Select is a convenience function to create a new Options reading only the given columns.
+func Select(columns ...string) *Options {
+	return NewWhere().Select(columns...)
+}
+
+// G is a convenience function to create a new Options grouping aggregate queries by columns.
+func G(columns ...string) *Options {
+	return NewWhere().G(columns...)
+}
+
 // T is a convenience function to create a new Options with tenant.
 func T(ctx context.Context) *Options {
-	return NewWhere().F(registeredTenant.Key, registeredTenant.ValueFunc(ctx))
+	return NewWhere().T(ctx)
 }
 
 // F is a convenience function to create a new Options with filters.
 func F(kvs ...any) *Options {
 	return NewWhere().F(kvs...)
 }
-
-// RegisterTenant registers a new tenant with the specified key and value function.
-func RegisterTenant(key string, valueFunc func(context.Context) string) {
-	registeredTenant = Tenant{
-		Key:       key,
-		ValueFunc: valueFunc,
-	}
-}
@@ -0,0 +1,81 @@
+package where
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/moweilong/mo/gormx"
+)
+
+type whereDoc struct {
+	ID        uint   `gorm:"primaryKey"`
+	OrgID     string `gorm:"size:32"`
+	ProjectID string `gorm:"size:32"`
+	Title     string `gorm:"size:64"`
+}
+
+// newDocDB opens an in-memory SQLite database holding docs 1 to 10, the odd ones of org o1
+// and the even ones of org o2, all of project p1.
+func newDocDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gormx.NewSQLite(&gormx.SQLiteOptions{Logger: logger.Discard})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, db.AutoMigrate(&whereDoc{}))
+
+	docs := make([]*whereDoc, 0, 10)
+	for i := 1; i <= 10; i++ {
+		org := "o1"
+		if i%2 == 0 {
+			org = "o2"
+		}
+		docs = append(docs, &whereDoc{ID: uint(i), OrgID: org, ProjectID: "p1", Title: "doc"})
+	}
+	require.NoError(t, db.Create(&docs).Error)
+	return db
+}
+
+func findDocIDs(t *testing.T, db *gorm.DB, opts *Options) []uint {
+	t.Helper()
+
+	var ids []uint
+	require.NoError(t, opts.Where(db.Model(&whereDoc{})).Order("id").Pluck("id", &ids).Error)
+	return ids
+}
+
+func TestOptionsWhere(t *testing.T) {
+	db := newDocDB(t)
+
+	t.Run("pagination", func(t *testing.T) {
+		assert.Equal(t, []uint{4, 5, 6}, findDocIDs(t, db, P(2, 3)))
+		assert.Equal(t, []uint{9, 10}, findDocIDs(t, db, O(8).L(5)))
+		assert.Len(t, findDocIDs(t, db, NewWhere()), 10)
+	})
+
+	t.Run("filters and queries", func(t *testing.T) {
+		assert.Equal(t, []uint{2, 4}, findDocIDs(t, db, F("org_id", "o2").Q("id < ?", 6)))
+		assert.Equal(t, []uint{3}, findDocIDs(t, db, F("org_id", "o1", "id", 3)))
+	})
+
+	t.Run("tenant filter", func(t *testing.T) {
+		RegisterTenant("org_id", tenantFromContext("org"))
+		RegisterTenant("project_id", tenantFromContext("project"))
+
+		ctx := context.WithValue(context.Background(), tenantCtxKey("org"), "o1")
+		ctx = context.WithValue(ctx, tenantCtxKey("project"), "p1")
+		assert.Equal(t, []uint{1, 3, 5, 7, 9}, findDocIDs(t, db, T(ctx)))
+		assert.Equal(t, []uint{3, 5}, findDocIDs(t, db, T(ctx).O(1).L(2)))
+
+		ctx = context.WithValue(ctx, tenantCtxKey("project"), "p2")
+		assert.Empty(t, findDocIDs(t, db, T(ctx)))
+	})
+}